@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"github.com/caarlos0/env/v6"
+	"github.com/thetkpark/cscms-temp-storage/config"
 	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/metrics"
 	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -14,40 +18,66 @@ import (
 )
 
 func main() {
+	start := time.Now()
 
-	// Get ENV
-	appENVs := ApplicationEnvironmentVariable{}
-	if err := env.Parse(&appENVs, env.Options{RequiredIfNoDef: true}); err != nil {
-		log.Fatalf("Unable to get env: %v", err.Error())
+	// Load config
+	cfg, err := config.Load(config.RequireDatabase, config.RequireStorage)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err.Error())
 	}
 
 	zapLogger, _ := zap.NewProduction()
-	if appENVs.Env == "development" {
+	if cfg.Env == "development" {
 		zapLogger, _ = zap.NewDevelopment()
 	}
 	defer zapLogger.Sync()
 	logger := zapLogger.Sugar()
+	ctx := context.Background()
 
 	// Open data store
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", appENVs.DB.Username, appENVs.DB.Password, appENVs.DB.Host, appENVs.DB.Port, appENVs.DB.DatabaseName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
 	if err != nil {
 		logger.Errorw("unable to open connection to db", "error", err.Error())
 	}
 
 	// Create disk storage manager
-	diskStorageManager, err := storage.NewDiskStorageManager(logger, appENVs.FileStoragePath)
+	diskStorageManager, err := storage.NewDiskStorageManager(logger, cfg.Storage.Path)
 	if err != nil {
 		logger.Errorw("unable to create disk storage manager", "error", err.Error())
 		os.Exit(1)
 	}
 	// Create file data store
-	fileDataStore, err := data.NewGormFileDataStore(db, time.Duration(appENVs.FileStoreMaxDuration)*time.Hour*24)
+	fileDataStore, err := data.NewGormFileDataStore(db, cfg.Storage.MaxStoreDuration())
 	if err != nil {
 		logger.Errorw("unable to create file data store", "error", err.Error())
 	}
+	// Create bundle data store
+	bundleDataStore, err := data.NewGormBundleDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create bundle data store", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create blob data store
+	blobDataStore, err := data.NewGormBlobDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create blob data store", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create webhook dispatcher to notify the owners of the expired files
+	webhookDataStore, err := data.NewGormWebhookDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create webhook data store", "error", err.Error())
+		os.Exit(1)
+	}
+	webhookDispatcher := webhook.NewHTTPDispatcher(logger, webhookDataStore, cfg.Webhook.MaxAttempts, cfg.Webhook.Backoff.Duration(), cfg.Webhook.Timeout.Duration())
+	// Create rate limit data store to remove the counters of the past windows
+	rateLimitDataStore, err := data.NewGormRateLimitDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create rate limit data store", "error", err.Error())
+		os.Exit(1)
+	}
 
-	fileLists, err := diskStorageManager.ListFiles()
+	fileLists, err := diskStorageManager.ListFiles(ctx)
 	if err != nil {
 		logger.Errorw("unable to list file", "error", err.Error())
 		os.Exit(1)
@@ -57,45 +87,93 @@ func main() {
 	isError := false
 
 	for _, fileName := range fileLists {
-		fileInfo, err := fileDataStore.FindByID(fileName)
+		var expiredFiles []model.File
+		// Shared content is kept while any of its files is not expired
+		blob, err := blobDataStore.FindByID(ctx, fileName)
 		if err != nil {
 			isError = true
-			logger.Errorw("Unable to query by file id", "error", err.Error())
+			logger.Errorw("Unable to query by blob id", "error", err.Error())
 			continue
 		}
+		if blob != nil {
+			activeCount, err := fileDataStore.CountActiveByBlobID(ctx, blob.ID)
+			if err != nil {
+				isError = true
+				logger.Errorw("Unable to count files of blob", "error", err.Error())
+				continue
+			}
+			if activeCount > 0 {
+				continue
+			}
+			// All the files of the blob are expired with its content
+			if expiredFiles, err = fileDataStore.FindAllByBlobID(ctx, blob.ID); err != nil {
+				isError = true
+				logger.Errorw("Unable to query files of blob", "error", err.Error())
+				continue
+			}
+			if err := blobDataStore.DeleteByID(ctx, blob.ID); err != nil {
+				isError = true
+				logger.Errorw("Unable to delete blob", "error", err.Error())
+				continue
+			}
+		} else {
+			// The content rewritten by the encrypt and rekey commands is not named by the file ID
+			fileInfo, err := fileDataStore.FindByStorageID(ctx, fileName)
+			if err != nil {
+				isError = true
+				logger.Errorw("Unable to query by file id", "error", err.Error())
+				continue
+			}
 
-		// Check if fileInfo is existed in db and expired_at is in the future
-		if fileInfo != nil && fileInfo.ExpiredAt.UTC().After(time.Now().UTC()) {
-			continue
+			// Check if fileInfo is existed in db and expired_at is in the future
+			if fileInfo != nil && fileInfo.ExpiredAt.UTC().After(time.Now().UTC()) {
+				continue
+			}
+			if fileInfo != nil {
+				expiredFiles = append(expiredFiles, *fileInfo)
+			}
 		}
 
 		// Delete expired file
-		if err := diskStorageManager.DeleteFile(fileName); err != nil {
+		if err := diskStorageManager.DeleteFile(ctx, fileName); err != nil {
 			// If failed -> continue to delete other file
 			isError = true
 			continue
 		}
 		deletedCount++
+		for i := range expiredFiles {
+			webhookDispatcher.Dispatch(expiredFiles[i].UserID, webhook.NewFileEvent(webhook.EventFileExpired, &expiredFiles[i]))
+		}
+	}
+	if expiredCount, err := bundleDataStore.DeleteExpired(ctx); err != nil {
+		isError = true
+		logger.Errorw("Unable to delete expired bundles", "error", err.Error())
+	} else {
+		logger.Info(fmt.Sprintf("Delete %d bundle", expiredCount))
+	}
+
+	if expiredCount, err := rateLimitDataStore.DeleteExpired(ctx); err != nil {
+		isError = true
+		logger.Errorw("Unable to delete expired rate limit counters", "error", err.Error())
+	} else {
+		logger.Info(fmt.Sprintf("Delete %d rate limit counter", expiredCount))
 	}
 
+	// The process exits after the events are delivered, run out of attempts or are abandoned after the timeout
+	waitCtx, cancel := context.WithTimeout(ctx, cfg.Webhook.ShutdownTimeout.Duration())
+	defer cancel()
+	webhookDispatcher.Wait(waitCtx)
+
 	if isError {
 		logger.Info("There is an failure")
 	}
 
 	logger.Info(fmt.Sprintf("Delete %d file", deletedCount))
-}
-
-type ApplicationEnvironmentVariable struct {
-	FileStoragePath      string `env:"STORAGE_PATH"`
-	FileStoreMaxDuration int    `env:"STORE_DURATION" envDefault:"30"`
-	Env                  string `env:"ENV" envDefault:"development"`
-	DB                   DatabaseEnvironmentVariable
-}
 
-type DatabaseEnvironmentVariable struct {
-	Username     string `env:"DB_USERNAME"`
-	Password     string `env:"DB_PASSWORD"`
-	Host         string `env:"DB_HOST"`
-	Port         string `env:"DB_PORT"`
-	DatabaseName string `env:"DB_DATABASE"`
+	// Report the result for alerting
+	if len(cfg.Metrics.PushgatewayURL) > 0 {
+		if err := metrics.PushCleanerResult(cfg.Metrics.PushgatewayURL, deletedCount, isError, time.Since(start)); err != nil {
+			logger.Errorw("unable to push cleaner result", "error", err.Error())
+		}
+	}
 }
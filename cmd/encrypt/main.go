@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/config"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Encrypt the files that were stored in plaintext before every upload is encrypted
+func main() {
+
+	// Load config
+	cfg, err := config.Load(config.RequireDatabase, config.RequireStorage, config.RequireEncryption)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err.Error())
+	}
+
+	zapLogger, _ := zap.NewProduction()
+	if cfg.Env == "development" {
+		zapLogger, _ = zap.NewDevelopment()
+	}
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	ctx := context.Background()
+
+	// Open data store
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
+	if err != nil {
+		logger.Errorw("unable to open connection to db", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Create disk storage manager
+	diskStorageManager, err := storage.NewDiskStorageManager(logger, cfg.Storage.Path)
+	if err != nil {
+		logger.Errorw("unable to create disk storage manager", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create file data store
+	fileDataStore, err := data.NewGormFileDataStore(db, cfg.Storage.MaxStoreDuration())
+	if err != nil {
+		logger.Errorw("unable to create file data store", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create blob data store to release the shared content of the encrypted files
+	blobDataStore, err := data.NewGormBlobDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create blob data store", "error", err.Error())
+		os.Exit(1)
+	}
+	keyProviderConfig, err := cfg.Encryption.KeyProviderConfig()
+	if err != nil {
+		logger.Errorw("unable to read encryption config", "error", err.Error())
+		os.Exit(1)
+	}
+	encryptionManager, err := encrypt.NewManagerFromConfig(logger, keyProviderConfig)
+	if err != nil {
+		logger.Errorw("unable to create encryption manager", "error", err.Error())
+		os.Exit(1)
+	}
+
+	files, err := fileDataStore.FindUnencrypted(ctx)
+	if err != nil {
+		logger.Errorw("unable to find unencrypted files", "error", err.Error())
+		os.Exit(1)
+	}
+
+	encryptedCount := 0
+	isError := false
+
+	for i := range files {
+		// Files that are already deleted from storage are left for the cleaner
+		exist, err := diskStorageManager.Exist(ctx, files[i].StorageKey())
+		if err != nil {
+			isError = true
+			continue
+		}
+		if !exist {
+			continue
+		}
+
+		plaintextFile := files[i]
+		if err := encryptFile(ctx, diskStorageManager, fileDataStore, encryptionManager, &files[i]); err != nil {
+			// If failed -> continue to encrypt other file
+			isError = true
+			logger.Errorw("unable to encrypt file", "error", err.Error(), "fileID", files[i].ID)
+			continue
+		}
+		encryptedCount++
+
+		// The plaintext is deleted after the file points to the encrypted content
+		if err := data.ReleaseContent(ctx, blobDataStore, diskStorageManager, &plaintextFile); err != nil {
+			isError = true
+			logger.Errorw("unable to delete plaintext file", "error", err.Error(), "fileID", files[i].ID)
+		}
+	}
+
+	if isError {
+		logger.Info("There is an failure")
+	}
+
+	logger.Info(fmt.Sprintf("Encrypt %d file", encryptedCount))
+}
+
+// encryptFile writes the encrypted copy under a new storage ID and points the file to it
+// in the same transaction that marks the file as encrypted, so the plaintext is kept if the transaction fails.
+// The hidden temporary file is skipped by the cleaner while it is written.
+func encryptFile(ctx context.Context, storageManager storage.FileManager, fileDataStore data.FileDataStore, encryptionManager encrypt.Manager, fileInfo *model.File) error {
+	tempName := fmt.Sprintf(".%s.encrypting", fileInfo.ID)
+	storageID := fmt.Sprintf("%s.%d", fileInfo.ID, time.Now().UnixNano())
+
+	plaintext, err := storageManager.OpenFile(ctx, fileInfo.StorageKey())
+	if err != nil {
+		return err
+	}
+	if closer, ok := plaintext.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	ciphertext, metadata, err := encryptionManager.Encrypt(ctx, plaintext)
+	if err != nil {
+		return err
+	}
+	fileInfo.Nonce = metadata.Nonce
+	fileInfo.KeyID = metadata.KeyID
+	fileInfo.WrappedKey = metadata.WrappedKey
+	if err := storageManager.WriteToNewFile(ctx, tempName, ciphertext); err != nil {
+		_ = storageManager.DeleteFile(ctx, tempName)
+		return err
+	}
+
+	// The encrypted content is not shared, so the file no longer uses the blob
+	fileInfo.BlobID = nil
+	fileInfo.StorageID = &storageID
+	err = fileDataStore.UpdateEncryption(ctx, fileInfo, func() error {
+		return storageManager.RenameFile(ctx, tempName, storageID)
+	})
+	if err != nil {
+		for _, name := range []string{tempName, storageID} {
+			if exist, _ := storageManager.Exist(ctx, name); exist {
+				_ = storageManager.DeleteFile(ctx, name)
+			}
+		}
+		return err
+	}
+	return nil
+}
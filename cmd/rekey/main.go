@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/config"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Move the files that are encrypted with the old keys to the current key.
+// Files with envelope encryption only get their data key wrapped again,
+// files from before envelope encryption are re-encrypted with a new data key.
+// The server keeps serving the files while this runs, because the old keys are still loaded there.
+func main() {
+
+	// Load config
+	cfg, err := config.Load(config.RequireDatabase, config.RequireStorage, config.RequireEncryption)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err.Error())
+	}
+
+	zapLogger, _ := zap.NewProduction()
+	if cfg.Env == "development" {
+		zapLogger, _ = zap.NewDevelopment()
+	}
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	ctx := context.Background()
+
+	// Open data store
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
+	if err != nil {
+		logger.Errorw("unable to open connection to db", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Create disk storage manager
+	diskStorageManager, err := storage.NewDiskStorageManager(logger, cfg.Storage.Path)
+	if err != nil {
+		logger.Errorw("unable to create disk storage manager", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create file data store
+	fileDataStore, err := data.NewGormFileDataStore(db, cfg.Storage.MaxStoreDuration())
+	if err != nil {
+		logger.Errorw("unable to create file data store", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create blob data store to release the shared content of the re-encrypted files
+	blobDataStore, err := data.NewGormBlobDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create blob data store", "error", err.Error())
+		os.Exit(1)
+	}
+	keyProviderConfig, err := cfg.Encryption.KeyProviderConfig()
+	if err != nil {
+		logger.Errorw("unable to read encryption config", "error", err.Error())
+		os.Exit(1)
+	}
+	encryptionManager, err := encrypt.NewManagerFromConfig(logger, keyProviderConfig)
+	if err != nil {
+		logger.Errorw("unable to create encryption manager", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Files without wrapped data key are found as well, so they are moved to envelope encryption
+	files, err := fileDataStore.FindToRekey(ctx, []string{encryptionManager.CurrentKeyID()})
+	if err != nil {
+		logger.Errorw("unable to find files with old key", "error", err.Error())
+		os.Exit(1)
+	}
+
+	rekeyedCount := 0
+	isError := false
+
+	for i := range files {
+		// Only the data key has to be wrapped again for files with envelope encryption
+		if len(files[i].WrappedKey) > 0 {
+			if err := rewrapFile(ctx, fileDataStore, encryptionManager, &files[i]); err != nil {
+				isError = true
+				logger.Errorw("unable to rewrap data key", "error", err.Error(), "fileID", files[i].ID)
+				continue
+			}
+			rekeyedCount++
+			continue
+		}
+
+		// Files that are already deleted from storage are left for the cleaner
+		exist, err := diskStorageManager.Exist(ctx, files[i].StorageKey())
+		if err != nil {
+			isError = true
+			continue
+		}
+		if !exist {
+			continue
+		}
+
+		oldFile := files[i]
+		if err := rekeyFile(ctx, diskStorageManager, fileDataStore, encryptionManager, &files[i]); err != nil {
+			// If failed -> continue to re-key other file
+			isError = true
+			logger.Errorw("unable to re-key file", "error", err.Error(), "fileID", files[i].ID)
+			continue
+		}
+		rekeyedCount++
+
+		// The old content is deleted after the file points to the re-encrypted content
+		if err := data.ReleaseContent(ctx, blobDataStore, diskStorageManager, &oldFile); err != nil {
+			isError = true
+			logger.Errorw("unable to delete old encrypted file", "error", err.Error(), "fileID", files[i].ID)
+		}
+
+		// Leave some disk bandwidth for the server
+		time.Sleep(cfg.Rekey.Delay.Duration())
+	}
+
+	if isError {
+		logger.Info("There is an failure")
+	}
+
+	logger.Info(fmt.Sprintf("Re-key %d file", rekeyedCount))
+}
+
+// rewrapFile wraps the data key of the file with the current KEK
+func rewrapFile(ctx context.Context, fileDataStore data.FileDataStore, encryptionManager *encrypt.EnvelopeEncryptionManager, fileInfo *model.File) error {
+	metadata, err := encryptionManager.Rewrap(ctx, &encrypt.Metadata{KeyID: fileInfo.KeyID, WrappedKey: fileInfo.WrappedKey})
+	if err != nil {
+		return err
+	}
+	fileInfo.KeyID = metadata.KeyID
+	fileInfo.WrappedKey = metadata.WrappedKey
+	return fileDataStore.UpdateEncryption(ctx, fileInfo, func() error {
+		return nil
+	})
+}
+
+// rekeyFile writes the copy encrypted with a new data key under a new storage ID and points the file to it
+// in the same transaction that updates the encryption metadata, so the old content is kept if the transaction fails.
+// The hidden temporary file is skipped by the cleaner while it is written.
+func rekeyFile(ctx context.Context, storageManager storage.FileManager, fileDataStore data.FileDataStore, encryptionManager encrypt.Manager, fileInfo *model.File) error {
+	tempName := fmt.Sprintf(".%s.rekeying", fileInfo.ID)
+	storageID := fmt.Sprintf("%s.%d", fileInfo.ID, time.Now().UnixNano())
+
+	file, err := storageManager.OpenFile(ctx, fileInfo.StorageKey())
+	if err != nil {
+		return err
+	}
+	if closer, ok := file.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	plaintext, err := encryptionManager.Decrypt(ctx, file, &encrypt.Metadata{Nonce: fileInfo.Nonce, KeyID: fileInfo.KeyID, WrappedKey: fileInfo.WrappedKey})
+	if err != nil {
+		return err
+	}
+	ciphertext, metadata, err := encryptionManager.Encrypt(ctx, plaintext)
+	if err != nil {
+		return err
+	}
+	fileInfo.Nonce = metadata.Nonce
+	fileInfo.KeyID = metadata.KeyID
+	fileInfo.WrappedKey = metadata.WrappedKey
+	if err := storageManager.WriteToNewFile(ctx, tempName, ciphertext); err != nil {
+		_ = storageManager.DeleteFile(ctx, tempName)
+		return err
+	}
+
+	// The content with the new data key is not shared, so the file no longer uses the blob
+	fileInfo.BlobID = nil
+	fileInfo.StorageID = &storageID
+	err = fileDataStore.UpdateEncryption(ctx, fileInfo, func() error {
+		return storageManager.RenameFile(ctx, tempName, storageID)
+	})
+	if err != nil {
+		for _, name := range []string{tempName, storageID} {
+			if exist, _ := storageManager.Exist(ctx, name); exist {
+				_ = storageManager.DeleteFile(ctx, name)
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/config"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"log"
+	"os"
+)
+
+// Assign the role of the user, e.g. role -user 1 -role admin.
+// The role is user, admin or one of the permanent file roles, so the first admin can be assigned without the server.
+func main() {
+	userID := flag.Uint("user", 0, "ID of the user")
+	role := flag.String("role", "", "New role of the user")
+	flag.Parse()
+
+	// Load config
+	cfg, err := config.Load(config.RequireDatabase)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err.Error())
+	}
+
+	zapLogger, _ := zap.NewProduction()
+	if cfg.Env == "development" {
+		zapLogger, _ = zap.NewDevelopment()
+	}
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	ctx := context.Background()
+
+	if *userID == 0 || !validRole(*role, cfg.Storage.PermanentFileRoles) {
+		logger.Errorw("user and role must be provided, role must be user, admin or one of the permanent file roles", "permanentFileRoles", cfg.Storage.PermanentFileRoles)
+		os.Exit(1)
+	}
+
+	// Open data store
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
+	if err != nil {
+		logger.Errorw("unable to open connection to db", "error", err.Error())
+		os.Exit(1)
+	}
+	userDataStore, err := data.NewGormUserDataStore(db)
+	if err != nil {
+		logger.Errorw("unable to create user data store", "error", err.Error())
+		os.Exit(1)
+	}
+
+	user, err := userDataStore.FindById(ctx, *userID)
+	if err != nil {
+		logger.Errorw("unable to find user", "error", err.Error())
+		os.Exit(1)
+	}
+	if user == nil {
+		logger.Errorw("user not found", "userID", *userID)
+		os.Exit(1)
+	}
+	if err := userDataStore.UpdateRole(ctx, user.ID, *role); err != nil {
+		logger.Errorw("unable to update role", "error", err.Error())
+		os.Exit(1)
+	}
+
+	logger.Info(fmt.Sprintf("Change role of user %d (%s) from %s to %s", user.ID, user.Email, user.Role, *role))
+}
+
+func validRole(role string, permanentFileRoles []string) bool {
+	if role == model.RoleUser || role == model.RoleAdmin {
+		return true
+	}
+	for _, permanentRole := range permanentFileRoles {
+		if role == permanentRole {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/config"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// Verify the stored files against the SHA-256 checksum recorded on upload
+// to find the files that are corrupted or tampered with on disk.
+// Exit with status 1 if any file does not match.
+func main() {
+
+	// Load config
+	cfg, err := config.Load(config.RequireDatabase, config.RequireStorage, config.RequireEncryption)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err.Error())
+	}
+
+	zapLogger, _ := zap.NewProduction()
+	if cfg.Env == "development" {
+		zapLogger, _ = zap.NewDevelopment()
+	}
+	defer zapLogger.Sync()
+	logger := zapLogger.Sugar()
+	ctx := context.Background()
+
+	// Open data store
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
+	if err != nil {
+		logger.Errorw("unable to open connection to db", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Create disk storage manager
+	diskStorageManager, err := storage.NewDiskStorageManager(logger, cfg.Storage.Path)
+	if err != nil {
+		logger.Errorw("unable to create disk storage manager", "error", err.Error())
+		os.Exit(1)
+	}
+	// Create file data store
+	fileDataStore, err := data.NewGormFileDataStore(db, cfg.Storage.MaxStoreDuration())
+	if err != nil {
+		logger.Errorw("unable to create file data store", "error", err.Error())
+		os.Exit(1)
+	}
+	keyProviderConfig, err := cfg.Encryption.KeyProviderConfig()
+	if err != nil {
+		logger.Errorw("unable to read encryption config", "error", err.Error())
+		os.Exit(1)
+	}
+	encryptionManager, err := encrypt.NewManagerFromConfig(logger, keyProviderConfig)
+	if err != nil {
+		logger.Errorw("unable to create encryption manager", "error", err.Error())
+		os.Exit(1)
+	}
+
+	files, err := fileDataStore.FindWithChecksum(ctx)
+	if err != nil {
+		logger.Errorw("unable to find files with checksum", "error", err.Error())
+		os.Exit(1)
+	}
+
+	verifiedCount := 0
+	corruptedCount := 0
+	isError := false
+
+	for i := range files {
+		// Files that are already deleted from storage are left for the cleaner
+		exist, err := diskStorageManager.Exist(ctx, files[i].StorageKey())
+		if err != nil {
+			isError = true
+			continue
+		}
+		if !exist {
+			continue
+		}
+
+		checksum, err := fileChecksum(ctx, diskStorageManager, encryptionManager, &files[i])
+		if err != nil {
+			// Authentication of the encrypted file fails if the content is modified
+			corruptedCount++
+			logger.Errorw("unable to read file", "error", err.Error(), "fileID", files[i].ID)
+		} else if checksum != files[i].Checksum {
+			corruptedCount++
+			logger.Errorw("checksum mismatch", "fileID", files[i].ID, "expected", files[i].Checksum, "actual", checksum)
+		} else {
+			verifiedCount++
+		}
+
+		// Leave some disk bandwidth for the server
+		time.Sleep(cfg.Scrub.Delay.Duration())
+	}
+
+	if isError {
+		logger.Info("There is an failure")
+	}
+
+	logger.Info(fmt.Sprintf("Verify %d file, %d file corrupted", verifiedCount, corruptedCount))
+	if corruptedCount > 0 {
+		os.Exit(1)
+	}
+}
+
+// fileChecksum decrypts the stored file and computes the SHA-256 checksum of the content
+func fileChecksum(ctx context.Context, storageManager storage.FileManager, encryptionManager encrypt.Manager, fileInfo *model.File) (string, error) {
+	file, err := storageManager.OpenFile(ctx, fileInfo.StorageKey())
+	if err != nil {
+		return "", err
+	}
+	if closer, ok := file.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	content := file
+	if fileInfo.Encrypted {
+		content, err = encryptionManager.Decrypt(ctx, file, &encrypt.Metadata{Nonce: fileInfo.Nonce, KeyID: fileInfo.KeyID, WrappedKey: fileInfo.WrappedKey})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
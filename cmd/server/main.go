@@ -1,29 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/markbates/goth"
+	"github.com/thetkpark/cscms-temp-storage/config"
 	"github.com/thetkpark/cscms-temp-storage/data"
 	"github.com/thetkpark/cscms-temp-storage/handlers"
 	"github.com/thetkpark/cscms-temp-storage/router"
 	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"github.com/thetkpark/cscms-temp-storage/service/fetch"
+	"github.com/thetkpark/cscms-temp-storage/service/health"
 	"github.com/thetkpark/cscms-temp-storage/service/jwt"
+	"github.com/thetkpark/cscms-temp-storage/service/metrics"
+	"github.com/thetkpark/cscms-temp-storage/service/ratelimit"
 	"github.com/thetkpark/cscms-temp-storage/service/storage"
 	"github.com/thetkpark/cscms-temp-storage/service/token"
+	"github.com/thetkpark/cscms-temp-storage/service/tracing"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/arsmn/fiber-swagger/v2"
-	"github.com/caarlos0/env/v6"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/markbates/goth/providers/github"
 	"github.com/markbates/goth/providers/google"
 	"github.com/shareed2k/goth_fiber"
@@ -34,13 +43,13 @@ import (
 // @version 1.0
 // @description This is documentation for CSCMS Storage API
 func main() {
-	appENVs := ApplicationEnvironmentVariable{}
-	if err := env.Parse(&appENVs, env.Options{RequiredIfNoDef: true}); err != nil {
-		log.Fatalln("Failed to get app ENVs: ", err)
+	cfg, err := config.Load(config.RequireDatabase, config.RequireStorage, config.RequireEncryption, config.RequireServer)
+	if err != nil {
+		log.Fatalln("Failed to load config: ", err)
 	}
 
 	zapLogger, _ := zap.NewProduction()
-	if appENVs.Env == "development" {
+	if cfg.Env == "development" {
 		zapLogger, _ = zap.NewDevelopment()
 	}
 	logger := zapLogger.Sugar()
@@ -51,30 +60,50 @@ func main() {
 		}
 	}(zapLogger)
 
-	// Create file in /tmp for livenessProbe
-	livenessProbeFilePath := "/tmp/cscms-storage-healthy"
-	if _, err := os.Create(livenessProbeFilePath); err != nil {
-		logger.Fatalw("Unable to create livenessProbe file", "error", err)
+	// Traces are exported to the OTLP collector if the endpoint is set
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: "cscms-storage",
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Fatalw("unable to create tracing exporter", "error", err)
 	}
-	defer func(name string) {
-		err := os.Remove(name)
-		if err != nil {
-			logger.Errorw("unable to delete liveness probe file", "error", err)
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorw("unable to flush traces", "error", err)
 		}
-	}(livenessProbeFilePath)
+	}()
 
-	app := router.NewFiberRouter()
+	app := router.NewFiberRouter(int(cfg.Limits.BodyLimit))
 
 	// Create data store
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", appENVs.DB.Username, appENVs.DB.Password, appENVs.DB.Host, appENVs.DB.Port, appENVs.DB.DatabaseName)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(cfg.DB.DSN()), &gorm.Config{})
 	if err != nil {
 		logger.Fatalw("unable to open sqlite db", "error", err)
 	}
-	gormFileDataStore, err := data.NewGormFileDataStore(db, time.Duration(appENVs.FileStoreMaxDuration)*time.Hour*24)
+	if err := db.Use(&data.TimeoutPlugin{Timeout: cfg.Timeout.Database.Duration()}); err != nil {
+		logger.Fatalw("unable to register gorm timeout plugin", "error", err)
+	}
+	if err := db.Use(&metrics.GormPlugin{}); err != nil {
+		logger.Fatalw("unable to register gorm metrics plugin", "error", err)
+	}
+	if err := db.Use(&tracing.GormPlugin{}); err != nil {
+		logger.Fatalw("unable to register gorm tracing plugin", "error", err)
+	}
+	gormFileDataStore, err := data.NewGormFileDataStore(db, cfg.Storage.MaxStoreDuration())
 	if err != nil {
 		logger.Fatalw("unable to run gorm migration on file table", "error", err)
 	}
+	gormBundleDataStore, err := data.NewGormBundleDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on bundle table", "error", err)
+	}
+	gormBlobDataStore, err := data.NewGormBlobDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on blob table", "error", err)
+	}
 	gormImageDataStore, err := data.NewGormImageDataStore(db)
 	if err != nil {
 		logger.Fatalw("unable to run gorm migration on image table", "error", err)
@@ -83,33 +112,108 @@ func main() {
 	if err != nil {
 		logger.Fatalw("unable to run gorm migration on user table", "error", err)
 	}
+	gormAuditDataStore, err := data.NewGormAuditDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on audit table", "error", err)
+	}
+	gormDownloadDataStore, err := data.NewGormDownloadDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on download table", "error", err)
+	}
+	gormWebhookDataStore, err := data.NewGormWebhookDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on webhook table", "error", err)
+	}
+	gormRateLimitDataStore, err := data.NewGormRateLimitDataStore(db)
+	if err != nil {
+		logger.Fatalw("unable to run gorm migration on rate limit table", "error", err)
+	}
 
 	// Create service managers for handler
-	sioEncryptionManager := encrypt.NewSIOEncryptionManager(logger, appENVs.MasterKey)
-	diskStorageManager, err := storage.NewDiskStorageManager(logger, appENVs.FileStoragePath)
+	keyProviderConfig, err := cfg.Encryption.KeyProviderConfig()
+	if err != nil {
+		logger.Fatalw("unable to read encryption config", "error", err)
+	}
+	envelopeEncryptionManager, err := encrypt.NewManagerFromConfig(logger, keyProviderConfig)
+	if err != nil {
+		logger.Fatalw("unable to create encryption manager", "error", err)
+	}
+	encryptionManager := tracing.NewEncryptionManager(envelopeEncryptionManager)
+	diskStorageManager, err := storage.NewDiskStorageManager(logger, cfg.Storage.Path)
 	if err != nil {
 		logger.Fatalw("unable to create disk storage manager", "error", err)
 	}
-	imageStorageManager, err := storage.NewAzureImageStorageManager(logger, appENVs.AzureBlobStorageConnectionString, appENVs.AzureBlobStorageContainerName)
+	fileStorageManager := tracing.NewFileManager("disk", metrics.NewFileManager("disk", storage.NewTimeoutFileManager(diskStorageManager, cfg.Timeout.Storage.Duration())))
+	azureImageStorageManager, err := storage.NewAzureImageStorageManager(logger, cfg.ImageStorage.ConnectionString, cfg.ImageStorage.ContainerName)
 	if err != nil {
 		logger.Fatalw("unable to azure image storage manager", "error", err)
 	}
-	jwtManager := jwt.NewJWTManager(appENVs.JWTSecret)
+	imageStorageManager := tracing.NewImageManager("azure", metrics.NewImageManager("azure", storage.NewTimeoutImageManager(azureImageStorageManager, cfg.Timeout.ImageStorage.Duration())))
+	jwtManager := jwt.NewJWTManager(cfg.JWTSecret)
 	tokenManager := token.NewNanoIDTokenManager()
+	downloadEventSink, err := event.NewSink(logger, cfg.DownloadEvent.Sink, cfg.DownloadEvent.File)
+	if err != nil {
+		logger.Fatalw("unable to create download event sink", "error", err)
+	}
+	fetchManager := fetch.NewHTTPFetchManager(logger, cfg.Limits.MaxRemoteFileSize.Int64(), cfg.Timeout.RemoteFetch.Duration())
+	webhookDispatcher := webhook.NewHTTPDispatcher(logger, gormWebhookDataStore, cfg.Webhook.MaxAttempts, cfg.Webhook.Backoff.Duration(), cfg.Webhook.Timeout.Duration())
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimit.Store, gormRateLimitDataStore, cfg.RateLimit.RedisURL)
+	if err != nil {
+		logger.Fatalw("unable to create rate limit store", "error", err)
+	}
+	rateLimiter := ratelimit.New(logger, rateLimitStore, handlers.RateLimitKey)
 
 	// Create handlers
-	fileHandler := handlers.NewFileRoutesHandler(logger, sioEncryptionManager, gormFileDataStore, diskStorageManager, tokenManager, time.Duration(appENVs.FileStoreMaxDuration)*time.Hour*24)
-	imageHandler := handlers.NewImageRouteHandler(logger, gormImageDataStore, imageStorageManager, tokenManager)
-	authHandler := handlers.NewAuthRouteHandler(logger, gormUserDataStore, jwtManager, tokenManager, appENVs.Entrypoint)
+	fileHandler := handlers.NewFileRoutesHandler(logger, encryptionManager, gormFileDataStore, gormBundleDataStore, gormUserDataStore, fileStorageManager, tokenManager, fetchManager, cfg.Storage.MaxStoreDuration(), cfg.Storage.PermanentFileRoles, gormBlobDataStore, cfg.Storage.DedupEnabled, downloadEventSink, gormAuditDataStore, gormDownloadDataStore, webhookDispatcher, handlers.UploadLimits{
+		MaxFileSize:     cfg.Limits.MaxFileSize.Int64(),
+		MaxBundleSize:   cfg.Limits.MaxBundleSize.Int64(),
+		MaxPasteSize:    cfg.Limits.MaxPasteSize.Int64(),
+		MaxArchiveFiles: cfg.Limits.MaxArchiveFiles,
+	}, cfg.Throttle.Download.Throttle())
+	imageHandler := handlers.NewImageRouteHandler(logger, gormImageDataStore, imageStorageManager, tokenManager, gormAuditDataStore, webhookDispatcher, cfg.Limits.MaxImageSize.Int64())
+	authHandler := handlers.NewAuthRouteHandler(logger, gormUserDataStore, jwtManager, tokenManager, cfg.Entrypoint, gormAuditDataStore)
+	auditHandler := handlers.NewAuditRouteHandler(logger, gormAuditDataStore)
+	webhookHandler := handlers.NewWebhookRouteHandler(logger, gormWebhookDataStore, tokenManager, webhookDispatcher)
+	// Liveness only checks what restarting the server can recover, readiness checks all dependencies
+	storageWritableChecker := health.WritableDirChecker(cfg.Storage.Path)
+	readiness := health.New(cfg.Health.Timeout.Duration()).
+		Add("database", health.DatabaseChecker(db)).
+		Add("storage", storageWritableChecker).
+		Add("image_storage", health.CheckerFunc(azureImageStorageManager.Ping)).
+		Add("disk_space", health.DiskSpaceChecker(cfg.Storage.Path, uint64(cfg.Health.MinFreeBytes), cfg.Health.MinFreePercent))
+	if redisStore, ok := rateLimitStore.(*ratelimit.RedisStore); ok {
+		readiness.Add("rate_limit_store", health.CheckerFunc(redisStore.Ping))
+	}
+	healthHandler := handlers.NewHealthRouteHandler(logger,
+		health.New(cfg.Health.Timeout.Duration()).
+			Add("storage", storageWritableChecker),
+		readiness,
+	)
 
-	app.Use(limiter.New(limiter.Config{
-		Expiration: time.Second * 5,
-		Max:        10,
+	// The probes are registered before the middlewares, so they are not rate limited or logged
+	app.Get("/healthz", healthHandler.Liveness)
+	app.Get("/readyz", healthHandler.Readiness)
+
+	app.Use(requestid.New())
+	app.Use(tracing.NewFiberMiddleware())
+	app.Use(handlers.NewAccessLogger(logger))
+	app.Use(metrics.NewFiberMiddleware())
+	// The flood guard is counted by IP in memory before the user is looked up, so the flood does not reach the database.
+	// Its limit is high, so the clients behind the same NAT are only separated by the upload, download and auth limits of their users.
+	floodLimiter := ratelimit.New(logger, ratelimit.NewMemoryStore(), ratelimit.IPKey)
+	app.Use(floodLimiter.Handler(ratelimit.Rule{
+		Name:   "flood",
+		Max:    int64(cfg.RateLimit.Max),
+		Window: cfg.RateLimit.Expiration.Duration(),
 	}))
+	uploadLimit := rateLimiter.Handler(cfg.RateLimit.Upload.Rule("upload"))
+	downloadLimit := rateLimiter.Handler(cfg.RateLimit.Download.Rule("download"))
+	authLimit := rateLimiter.Handler(cfg.RateLimit.Auth.Rule("auth"))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "https://storage.cscms.me, http://localhost:3000",
+		AllowOrigins:     strings.Join(cfg.CORS.AllowOrigins, ", "),
 		AllowMethods:     "GET POST PATCH DELETE",
 		AllowCredentials: true,
+		ExposeHeaders:    "X-Client-Encrypted, X-Client-Encryption-Format, X-Decrypted-Size, X-Request-ID",
 	}))
 	app.Use(compress.New(compress.Config{
 		Next: func(c *fiber.Ctx) bool {
@@ -128,36 +232,66 @@ func main() {
 		})
 	})
 
+	app.Get("/metrics", metrics.Handler(cfg.Metrics.Token))
+
 	apiPath := app.Group("/api", authHandler.ParseUser)
 
 	filePath := apiPath.Group("/file")
-	filePath.Post("/", fileHandler.UploadFile)
+	filePath.Post("/", uploadLimit, fileHandler.UploadFile)
 	filePath.Get("/", authHandler.AuthenticatedOnly, fileHandler.GetOwnFiles)
-	filePath.Patch("/:fileID", authHandler.AuthenticatedOnly, fileHandler.IsOwnFile, fileHandler.EditToken)
+	filePath.Post("/archive", authHandler.AuthenticatedOnly, downloadLimit, fileHandler.DownloadArchive)
+	filePath.Post("/remote", authHandler.AuthenticatedOnly, uploadLimit, fileHandler.UploadRemoteFile)
+	filePath.Get("/remote/:uploadID", authHandler.AuthenticatedOnly, fileHandler.GetRemoteUpload)
+	filePath.Get("/:fileID/analytics", authHandler.AuthenticatedOnly, fileHandler.IsOwnFile, fileHandler.GetFileAnalytics)
+	filePath.Patch("/:fileID", authHandler.AuthenticatedOnly, fileHandler.IsOwnFile, fileHandler.EditFile)
 	filePath.Delete("/:fileID", authHandler.AuthenticatedOnly, fileHandler.IsOwnFile, fileHandler.DeleteFile)
 
+	bundlePath := apiPath.Group("/bundle")
+	bundlePath.Post("/", uploadLimit, fileHandler.UploadBundle)
+	bundlePath.Get("/", authHandler.AuthenticatedOnly, fileHandler.GetOwnBundles)
+	bundlePath.Get("/:token", fileHandler.GetBundleInfo)
+
+	apiPath.Post("/paste", uploadLimit, fileHandler.UploadPaste)
+
+	apiPath.Get("/audit", authHandler.AuthenticatedOnly, auditHandler.GetOwnAuditEvents)
+	apiPath.Get("/admin/audit", authHandler.AuthenticatedOnly, authHandler.AdminOnly, auditHandler.GetAuditEvents)
+
+	webhookPath := apiPath.Group("/webhook", authHandler.AuthenticatedOnly)
+	webhookPath.Post("/", webhookHandler.CreateWebhook)
+	webhookPath.Get("/", webhookHandler.GetOwnWebhooks)
+	webhookPath.Delete("/:webhookID", webhookHandler.IsOwnWebhook, webhookHandler.DeleteWebhook)
+	webhookPath.Get("/:webhookID/deliveries", webhookHandler.IsOwnWebhook, webhookHandler.GetWebhookDeliveries)
+	webhookPath.Post("/:webhookID/test", webhookHandler.IsOwnWebhook, webhookHandler.TestWebhook)
+
 	imagePath := apiPath.Group("/image")
-	imagePath.Post("/", imageHandler.UploadImage)
+	imagePath.Post("/", uploadLimit, imageHandler.UploadImage)
 	imagePath.Get("/", authHandler.AuthenticatedOnly, imageHandler.GetOwnImages)
 	imagePath.Delete("/:imageID", authHandler.AuthenticatedOnly, imageHandler.IsOwnImage, imageHandler.DeleteImage)
 
 	// User Authentication with Oauth
 	goth.UseProviders(
-		github.New(appENVs.OauthGitHubClientSecret, appENVs.OauthGitHubSecretKey, fmt.Sprintf("%s/auth/github/callback", appENVs.Entrypoint), "user:email"),
-		google.New(appENVs.OAuthGoogleClientSecret, appENVs.OAuthGoogleSecretKey, fmt.Sprintf("%s/auth/google/callback", appENVs.Entrypoint), "email", "profile"))
+		github.New(cfg.OAuth.GitHubClientID, cfg.OAuth.GitHubSecretKey, fmt.Sprintf("%s/auth/github/callback", cfg.Entrypoint), "user:email"),
+		google.New(cfg.OAuth.GoogleClientID, cfg.OAuth.GoogleSecretKey, fmt.Sprintf("%s/auth/google/callback", cfg.Entrypoint), "email", "profile"))
 
-	authPath := app.Group("/auth")
+	authPath := app.Group("/auth", authLimit)
 	authPath.Get("/logout", authHandler.Logout)
 	authPath.Get("/user", authHandler.ParseUser, authHandler.AuthenticatedOnly, authHandler.GetUserInfo)
 	authPath.Get("/:provider", goth_fiber.BeginAuthHandler)
 	authPath.Get("/:provider/callback", authHandler.OauthProviderCallback)
-	apiPath.Post("/auth/token", authHandler.ParseUser, authHandler.AuthenticatedOnly, authHandler.GenerateAPIToken)
+	apiPath.Post("/auth/token", authLimit, authHandler.AuthenticatedOnly, authHandler.GenerateAPIToken)
 
 	// Other routes
 	app.Static("/", "./client/build")
 	app.Static("/404", "./client/build")
 	app.Get("/swagger/*", swagger.Handler)
-	app.Get("/:token", fileHandler.GetFile)
+	app.Get("/p/:token", fileHandler.GetPastePage)
+	app.Get("/p/:token/raw", authHandler.ParseUser, downloadLimit, fileHandler.GetPasteRaw)
+	app.Get("/b/:token", fileHandler.GetBundlePage)
+	app.Get("/b/:token/zip", authHandler.ParseUser, downloadLimit, fileHandler.GetBundleArchive)
+	app.Get("/b/:token/:fileID", authHandler.ParseUser, downloadLimit, fileHandler.GetBundleFile)
+	app.Get("/:token", fileHandler.GetFilePage)
+	app.Get("/:token/download", authHandler.ParseUser, downloadLimit, fileHandler.GetFile)
+	app.Get("/:token/preview", authHandler.ParseUser, downloadLimit, fileHandler.GetFilePreview)
 
 	// Graceful Shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -168,32 +302,13 @@ func main() {
 		_ = app.Shutdown()
 	}()
 
-	if err := app.Listen(fmt.Sprintf(":%s", appENVs.Port)); err != nil {
-		logger.Fatalw(fmt.Sprintf("unable to start server on %s", appENVs.Port), "error", err)
+	if err := app.Listen(fmt.Sprintf(":%s", cfg.Port)); err != nil {
+		logger.Fatalw(fmt.Sprintf("unable to start server on %s", cfg.Port), "error", err)
 	}
-}
-
-type ApplicationEnvironmentVariable struct {
-	MasterKey                        string `env:"MASTER_KEY"`
-	FileStoragePath                  string `env:"STORAGE_PATH"`
-	FileStoreMaxDuration             int    `env:"STORE_DURATION" envDefault:"30"`
-	AzureBlobStorageConnectionString string `env:"AZSTORAGE_CONNECTION_STRING"`
-	AzureBlobStorageContainerName    string `env:"AZSTORAGE_CONTAINER_NAME"`
-	Port                             string `env:"PORT"`
-	DB                               DatabaseEnvironmentVariable
-	OauthGitHubClientSecret          string `env:"GITHUB_OAUTH_CLIENT_ID"`
-	OauthGitHubSecretKey             string `env:"GITHUB_OAUTH_SECRET_KEY"`
-	OAuthGoogleClientSecret          string `env:"GOOGLE_OAUTH_CLIENT_ID"`
-	OAuthGoogleSecretKey             string `env:"GOOGLE_OAUTH_SECRET_KEY"`
-	Entrypoint                       string `env:"ENTRYPOINT"`
-	Env                              string `env:"ENV" envDefault:"development"`
-	JWTSecret                        string `env:"JWT_SECRET"`
-}
 
-type DatabaseEnvironmentVariable struct {
-	Username     string `env:"DB_USERNAME"`
-	Password     string `env:"DB_PASSWORD"`
-	Host         string `env:"DB_HOST"`
-	Port         string `env:"DB_PORT"`
-	DatabaseName string `env:"DB_DATABASE"`
+	// The dispatched webhook events are delivered, run out of attempts or abandoned after the timeout before the process exits
+	logger.Info("Waiting for webhook deliveries...")
+	waitCtx, cancel := context.WithTimeout(context.Background(), cfg.Webhook.ShutdownTimeout.Duration())
+	defer cancel()
+	webhookDispatcher.Wait(waitCtx)
 }
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v6"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/ratelimit"
+	"github.com/thetkpark/cscms-temp-storage/service/throttle"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileEnv is the environment variable of the path to the YAML or TOML config file
+const FileEnv = "CONFIG_FILE"
+
+// Config is the settings of the server and the jobs.
+// The values start from Default, then the config file is applied and the environment variables override both.
+type Config struct {
+	Env           string              `yaml:"env" toml:"env" env:"ENV"`
+	Port          string              `yaml:"port" toml:"port" env:"PORT"`
+	Entrypoint    string              `yaml:"entrypoint" toml:"entrypoint" env:"ENTRYPOINT"`
+	JWTSecret     string              `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	Storage       StorageConfig       `yaml:"storage" toml:"storage"`
+	ImageStorage  ImageStorageConfig  `yaml:"image_storage" toml:"image_storage"`
+	DB            DatabaseConfig      `yaml:"db" toml:"db"`
+	Encryption    EncryptionConfig    `yaml:"encryption" toml:"encryption"`
+	OAuth         OAuthConfig         `yaml:"oauth" toml:"oauth"`
+	Timeout       TimeoutConfig       `yaml:"timeout" toml:"timeout"`
+	Limits        LimitsConfig        `yaml:"limits" toml:"limits"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit" toml:"rate_limit"`
+	Throttle      ThrottleConfig      `yaml:"throttle" toml:"throttle"`
+	CORS          CORSConfig          `yaml:"cors" toml:"cors"`
+	Metrics       MetricsConfig       `yaml:"metrics" toml:"metrics"`
+	Tracing       TracingConfig       `yaml:"tracing" toml:"tracing"`
+	DownloadEvent DownloadEventConfig `yaml:"download_event" toml:"download_event"`
+	Webhook       WebhookConfig       `yaml:"webhook" toml:"webhook"`
+	Health        HealthConfig        `yaml:"health" toml:"health"`
+	Rekey         JobConfig           `yaml:"rekey" toml:"rekey" envPrefix:"REKEY_"`
+	Scrub         JobConfig           `yaml:"scrub" toml:"scrub" envPrefix:"SCRUB_"`
+}
+
+type StorageConfig struct {
+	Path string `yaml:"path" toml:"path" env:"STORAGE_PATH"`
+	// StoreDuration is the maximum number of days the file is stored
+	StoreDuration      int      `yaml:"store_duration" toml:"store_duration" env:"STORE_DURATION"`
+	PermanentFileRoles []string `yaml:"permanent_file_roles" toml:"permanent_file_roles" env:"PERMANENT_FILE_ROLES" envSeparator:","`
+	DedupEnabled       bool     `yaml:"dedup_enabled" toml:"dedup_enabled" env:"DEDUP_ENABLED"`
+}
+
+// MaxStoreDuration is the store duration as time.Duration
+func (c StorageConfig) MaxStoreDuration() time.Duration {
+	return time.Duration(c.StoreDuration) * time.Hour * 24
+}
+
+type ImageStorageConfig struct {
+	ConnectionString string `yaml:"connection_string" toml:"connection_string" env:"AZSTORAGE_CONNECTION_STRING"`
+	ContainerName    string `yaml:"container_name" toml:"container_name" env:"AZSTORAGE_CONTAINER_NAME"`
+}
+
+type DatabaseConfig struct {
+	Username     string `yaml:"username" toml:"username" env:"DB_USERNAME"`
+	Password     string `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Host         string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port         string `yaml:"port" toml:"port" env:"DB_PORT"`
+	DatabaseName string `yaml:"database" toml:"database" env:"DB_DATABASE"`
+}
+
+// DSN is the data source name of the MySQL driver
+func (c DatabaseConfig) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", c.Username, c.Password, c.Host, c.Port, c.DatabaseName)
+}
+
+type EncryptionConfig struct {
+	KeyProvider   string   `yaml:"key_provider" toml:"key_provider" env:"KEY_PROVIDER"`
+	MasterKey     string   `yaml:"master_key" toml:"master_key" env:"MASTER_KEY"`
+	MasterKeyID   string   `yaml:"master_key_id" toml:"master_key_id" env:"MASTER_KEY_ID"`
+	OldMasterKeys []string `yaml:"old_master_keys" toml:"old_master_keys" env:"OLD_MASTER_KEYS" envSeparator:","`
+	KeyFile       string   `yaml:"key_file" toml:"key_file" env:"KEY_FILE"`
+	VaultAddress  string   `yaml:"vault_address" toml:"vault_address" env:"VAULT_ADDR"`
+	VaultToken    string   `yaml:"vault_token" toml:"vault_token" env:"VAULT_TOKEN"`
+	VaultMount    string   `yaml:"vault_transit_mount" toml:"vault_transit_mount" env:"VAULT_TRANSIT_MOUNT"`
+	VaultKeyName  string   `yaml:"vault_transit_key" toml:"vault_transit_key" env:"VAULT_TRANSIT_KEY"`
+	VaultTimeout  Duration `yaml:"vault_timeout" toml:"vault_timeout" env:"VAULT_TIMEOUT"`
+}
+
+// KeyProviderConfig returns the config of the key provider with the old master keys parsed
+func (c EncryptionConfig) KeyProviderConfig() (encrypt.KeyProviderConfig, error) {
+	oldMasterKeys, err := encrypt.ParseMasterKeys(c.OldMasterKeys)
+	if err != nil {
+		return encrypt.KeyProviderConfig{}, fmt.Errorf("unable to parse old master keys: %w", err)
+	}
+	return encrypt.KeyProviderConfig{
+		Provider:      c.KeyProvider,
+		MasterKeyID:   c.MasterKeyID,
+		MasterKey:     c.MasterKey,
+		OldMasterKeys: oldMasterKeys,
+		KeyFile:       c.KeyFile,
+		Vault: encrypt.VaultConfig{
+			Address: c.VaultAddress,
+			Token:   c.VaultToken,
+			Mount:   c.VaultMount,
+			KeyName: c.VaultKeyName,
+			Timeout: c.VaultTimeout.Duration(),
+		},
+	}, nil
+}
+
+type OAuthConfig struct {
+	GitHubClientID  string `yaml:"github_client_id" toml:"github_client_id" env:"GITHUB_OAUTH_CLIENT_ID"`
+	GitHubSecretKey string `yaml:"github_secret_key" toml:"github_secret_key" env:"GITHUB_OAUTH_SECRET_KEY"`
+	GoogleClientID  string `yaml:"google_client_id" toml:"google_client_id" env:"GOOGLE_OAUTH_CLIENT_ID"`
+	GoogleSecretKey string `yaml:"google_secret_key" toml:"google_secret_key" env:"GOOGLE_OAUTH_SECRET_KEY"`
+}
+
+type TimeoutConfig struct {
+	Database     Duration `yaml:"database" toml:"database" env:"DB_TIMEOUT"`
+	Storage      Duration `yaml:"storage" toml:"storage" env:"STORAGE_TIMEOUT"`
+	ImageStorage Duration `yaml:"image_storage" toml:"image_storage" env:"IMAGE_STORAGE_TIMEOUT"`
+	RemoteFetch  Duration `yaml:"remote_fetch" toml:"remote_fetch" env:"REMOTE_FETCH_TIMEOUT"`
+}
+
+// LimitsConfig is the maximum sizes of the requests and the uploaded content
+type LimitsConfig struct {
+	// BodyLimit is the maximum size of the request body, the uploads larger than this are rejected before the handlers
+	BodyLimit         ByteSize `yaml:"body_limit" toml:"body_limit" env:"BODY_LIMIT"`
+	MaxFileSize       ByteSize `yaml:"max_file_size" toml:"max_file_size" env:"MAX_FILE_SIZE"`
+	MaxBundleSize     ByteSize `yaml:"max_bundle_size" toml:"max_bundle_size" env:"MAX_BUNDLE_SIZE"`
+	MaxPasteSize      ByteSize `yaml:"max_paste_size" toml:"max_paste_size" env:"MAX_PASTE_SIZE"`
+	MaxImageSize      ByteSize `yaml:"max_image_size" toml:"max_image_size" env:"MAX_IMAGE_SIZE"`
+	MaxRemoteFileSize ByteSize `yaml:"max_remote_file_size" toml:"max_remote_file_size" env:"MAX_REMOTE_FILE_SIZE"`
+	MaxArchiveFiles   int      `yaml:"max_archive_files" toml:"max_archive_files" env:"MAX_ARCHIVE_FILES"`
+}
+
+// RateLimitConfig allows Max requests from the same IP in every Expiration on all routes to guard against floods,
+// the guard is counted in the memory of each replica. The upload, download and auth routes are also limited
+// by their own rule for each user, counted in the Store.
+type RateLimitConfig struct {
+	Max        int      `yaml:"max" toml:"max" env:"RATE_LIMIT_MAX"`
+	Expiration Duration `yaml:"expiration" toml:"expiration" env:"RATE_LIMIT_EXPIRATION"`
+	// Store is where the hits are counted, memory, database or redis. Only database and redis are shared by the replicas.
+	Store    string        `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE"`
+	RedisURL string        `yaml:"redis_url" toml:"redis_url" env:"RATE_LIMIT_REDIS_URL"`
+	Upload   RateLimitRule `yaml:"upload" toml:"upload" envPrefix:"RATE_LIMIT_UPLOAD_"`
+	Download RateLimitRule `yaml:"download" toml:"download" envPrefix:"RATE_LIMIT_DOWNLOAD_"`
+	Auth     RateLimitRule `yaml:"auth" toml:"auth" envPrefix:"RATE_LIMIT_AUTH_"`
+}
+
+// RateLimitRule allows Max requests and Bandwidth bytes from the same client in every Window, zero disables the limit
+type RateLimitRule struct {
+	Max       int      `yaml:"max" toml:"max" env:"MAX"`
+	Bandwidth ByteSize `yaml:"bandwidth" toml:"bandwidth" env:"BANDWIDTH"`
+	Window    Duration `yaml:"window" toml:"window" env:"WINDOW"`
+}
+
+func (r RateLimitRule) Rule(name string) ratelimit.Rule {
+	return ratelimit.Rule{
+		Name:      name,
+		Max:       int64(r.Max),
+		Bandwidth: r.Bandwidth.Int64(),
+		Window:    r.Window.Duration(),
+	}
+}
+
+// ThrottleConfig caps the throughput of the file downloads.
+// The uploads are not capped, because the server receives the whole request body before the handlers.
+type ThrottleConfig struct {
+	Download ThrottleRule `yaml:"download" toml:"download" envPrefix:"THROTTLE_DOWNLOAD_"`
+}
+
+// ThrottleRule is the caps in bytes per second of all the connections together and of each connection by its user,
+// zero disables the cap
+type ThrottleRule struct {
+	Global        ByteSize `yaml:"global" toml:"global" env:"GLOBAL"`
+	Anonymous     ByteSize `yaml:"anonymous" toml:"anonymous" env:"ANONYMOUS"`
+	Authenticated ByteSize `yaml:"authenticated" toml:"authenticated" env:"AUTHENTICATED"`
+	Admin         ByteSize `yaml:"admin" toml:"admin" env:"ADMIN"`
+}
+
+func (r ThrottleRule) Throttle() *throttle.Throttle {
+	return throttle.New(r.Global.Int64(), throttle.Limits{
+		Anonymous:     r.Anonymous.Int64(),
+		Authenticated: r.Authenticated.Int64(),
+		Admin:         r.Admin.Int64(),
+	})
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" toml:"allow_origins" env:"CORS_ALLOW_ORIGINS" envSeparator:","`
+}
+
+type MetricsConfig struct {
+	Token          string `yaml:"token" toml:"token" env:"METRICS_TOKEN"`
+	PushgatewayURL string `yaml:"pushgateway_url" toml:"pushgateway_url" env:"PUSHGATEWAY_URL"`
+}
+
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" env:"TRACING_ENDPOINT"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure" env:"TRACING_INSECURE"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type DownloadEventConfig struct {
+	Sink string `yaml:"sink" toml:"sink" env:"DOWNLOAD_EVENT_SINK"`
+	File string `yaml:"file" toml:"file" env:"DOWNLOAD_EVENT_FILE"`
+}
+
+type WebhookConfig struct {
+	MaxAttempts int      `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	Backoff     Duration `yaml:"backoff" toml:"backoff" env:"WEBHOOK_BACKOFF"`
+	Timeout     Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// ShutdownTimeout is how long the process waits for the pending deliveries before it abandons them
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"WEBHOOK_SHUTDOWN_TIMEOUT"`
+}
+
+type HealthConfig struct {
+	Timeout        Duration `yaml:"timeout" toml:"timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	MinFreeBytes   ByteSize `yaml:"min_free_bytes" toml:"min_free_bytes" env:"HEALTH_MIN_FREE_BYTES"`
+	MinFreePercent float64  `yaml:"min_free_percent" toml:"min_free_percent" env:"HEALTH_MIN_FREE_PERCENT"`
+}
+
+// JobConfig is the settings of the rekey and scrub jobs, the environment variables are prefixed by the job name
+type JobConfig struct {
+	// Delay is the pause between the files to limit the load on the storage
+	Delay Duration `yaml:"delay" toml:"delay" env:"DELAY"`
+}
+
+// Default returns the config with the default values, the values without default must be set by the file or the environment variables
+func Default() *Config {
+	return &Config{
+		Env: "development",
+		Storage: StorageConfig{
+			StoreDuration:      30,
+			PermanentFileRoles: []string{"admin"},
+		},
+		Encryption: EncryptionConfig{
+			KeyProvider:  "static",
+			MasterKeyID:  "default",
+			VaultMount:   "transit",
+			VaultTimeout: Duration(10 * time.Second),
+		},
+		Timeout: TimeoutConfig{
+			Database:     Duration(10 * time.Second),
+			Storage:      Duration(10 * time.Minute),
+			ImageStorage: Duration(time.Minute),
+			RemoteFetch:  Duration(10 * time.Minute),
+		},
+		Limits: LimitsConfig{
+			BodyLimit:         150 << 20,
+			MaxFileSize:       100 << 20,
+			MaxBundleSize:     100 << 20,
+			MaxPasteSize:      1 << 20,
+			MaxImageSize:      5 << 20,
+			MaxRemoteFileSize: 100 << 20,
+			MaxArchiveFiles:   100,
+		},
+		RateLimit: RateLimitConfig{
+			Max:        600,
+			Expiration: Duration(time.Minute),
+			Store:      "memory",
+			Upload: RateLimitRule{
+				Max:       60,
+				Bandwidth: 2 << 30,
+				Window:    Duration(time.Hour),
+			},
+			Download: RateLimitRule{
+				Max:       600,
+				Bandwidth: 10 << 30,
+				Window:    Duration(time.Hour),
+			},
+			Auth: RateLimitRule{
+				Max:    20,
+				Window: Duration(time.Minute),
+			},
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"https://storage.cscms.me", "http://localhost:3000"},
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		DownloadEvent: DownloadEventConfig{
+			Sink: "log",
+		},
+		Webhook: WebhookConfig{
+			MaxAttempts:     5,
+			Backoff:         Duration(30 * time.Second),
+			Timeout:         Duration(10 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Health: HealthConfig{
+			Timeout:        Duration(5 * time.Second),
+			MinFreeBytes:   1 << 30,
+			MinFreePercent: 5,
+		},
+	}
+}
+
+// Load reads the config file in CONFIG_FILE if it is set, then applies the environment variables and validates the config
+func Load(required ...Requirement) (*Config, error) {
+	return LoadFile(os.Getenv(FileEnv), required...)
+}
+
+// LoadFile is Load with the path of the config file, the file is skipped if the path is empty
+func LoadFile(path string, required ...Requirement) (*Config, error) {
+	config := Default()
+	if len(path) > 0 {
+		if err := config.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	// Only the environment variables that are set override the values
+	if err := env.Parse(config); err != nil {
+		return nil, fmt.Errorf("unable to parse environment variables: %w", err)
+	}
+	if err := config.Validate(required...); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readFile decodes the file by its extension, unknown keys are rejected to catch typos
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.UnmarshalStrict(content, c); err != nil {
+			return fmt.Errorf("unable to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(content), c)
+		if err != nil {
+			return fmt.Errorf("unable to parse config file %s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown key %s in config file %s", undecoded[0], path)
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func setEnv(t *testing.T, key string, value string) {
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		_ = os.Unsetenv(key)
+	})
+}
+
+func TestLoadFileDefault(t *testing.T) {
+	config, err := LoadFile("")
+	require.NoError(t, err)
+	require.Equal(t, Default(), config)
+	require.Equal(t, 30*24*time.Hour, config.Storage.MaxStoreDuration())
+	require.Equal(t, int64(100<<20), config.Limits.MaxFileSize.Int64())
+}
+
+func TestLoadFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+port: "8080"
+storage:
+  path: /data
+  permanent_file_roles: [admin, staff]
+db:
+  host: mysql
+timeout:
+  database: 3s
+limits:
+  body_limit: 200MB
+  max_file_size: 150MB
+  max_paste_size: 512KB
+rate_limit:
+  max: 100
+  expiration: 1m
+cors:
+  allow_origins:
+    - https://example.com
+`)
+	config, err := LoadFile(path, RequireStorage)
+	require.NoError(t, err)
+	require.Equal(t, "8080", config.Port)
+	require.Equal(t, "/data", config.Storage.Path)
+	require.Equal(t, []string{"admin", "staff"}, config.Storage.PermanentFileRoles)
+	require.Equal(t, "mysql", config.DB.Host)
+	require.Equal(t, 3*time.Second, config.Timeout.Database.Duration())
+	require.Equal(t, ByteSize(200<<20), config.Limits.BodyLimit)
+	require.Equal(t, ByteSize(150<<20), config.Limits.MaxFileSize)
+	require.Equal(t, ByteSize(512<<10), config.Limits.MaxPasteSize)
+	require.Equal(t, 100, config.RateLimit.Max)
+	require.Equal(t, time.Minute, config.RateLimit.Expiration.Duration())
+	require.Equal(t, []string{"https://example.com"}, config.CORS.AllowOrigins)
+	// The values that are not in the file keep the default
+	require.Equal(t, 30, config.Storage.StoreDuration)
+	require.Equal(t, ByteSize(5<<20), config.Limits.MaxImageSize)
+}
+
+func TestLoadFileTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+port = "8080"
+
+[storage]
+path = "/data"
+dedup_enabled = true
+
+[limits]
+max_image_size = "10MB"
+max_archive_files = 20
+
+[webhook]
+backoff = "1m30s"
+
+[rekey]
+delay = "100ms"
+`)
+	config, err := LoadFile(path, RequireStorage)
+	require.NoError(t, err)
+	require.Equal(t, "8080", config.Port)
+	require.Equal(t, "/data", config.Storage.Path)
+	require.True(t, config.Storage.DedupEnabled)
+	require.Equal(t, ByteSize(10<<20), config.Limits.MaxImageSize)
+	require.Equal(t, 20, config.Limits.MaxArchiveFiles)
+	require.Equal(t, 90*time.Second, config.Webhook.Backoff.Duration())
+	require.Equal(t, 100*time.Millisecond, config.Rekey.Delay.Duration())
+	require.Equal(t, Duration(0), config.Scrub.Delay)
+}
+
+func TestLoadFileEnvOverride(t *testing.T) {
+	path := writeConfigFile(t, "config.yml", `
+storage:
+  path: /data
+  store_duration: 7
+rate_limit:
+  max: 100
+`)
+	setEnv(t, "STORAGE_PATH", "/mnt/storage")
+	setEnv(t, "RATE_LIMIT_EXPIRATION", "10s")
+	setEnv(t, "MAX_FILE_SIZE", "50MB")
+	setEnv(t, "CORS_ALLOW_ORIGINS", "https://a.example.com,https://b.example.com")
+	setEnv(t, "SCRUB_DELAY", "2s")
+	setEnv(t, "RATE_LIMIT_UPLOAD_MAX", "5")
+	setEnv(t, "RATE_LIMIT_DOWNLOAD_BANDWIDTH", "1GB")
+	setEnv(t, "THROTTLE_DOWNLOAD_AUTHENTICATED", "2MB")
+
+	config, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/storage", config.Storage.Path)
+	require.Equal(t, 7, config.Storage.StoreDuration)
+	require.Equal(t, 100, config.RateLimit.Max)
+	require.Equal(t, 10*time.Second, config.RateLimit.Expiration.Duration())
+	require.Equal(t, ByteSize(50<<20), config.Limits.MaxFileSize)
+	require.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, config.CORS.AllowOrigins)
+	require.Equal(t, 2*time.Second, config.Scrub.Delay.Duration())
+	require.Equal(t, Duration(0), config.Rekey.Delay)
+	require.Equal(t, 5, config.RateLimit.Upload.Max)
+	require.Equal(t, time.Hour, config.RateLimit.Upload.Window.Duration())
+	require.Equal(t, ByteSize(1<<30), config.RateLimit.Download.Bandwidth)
+	require.Equal(t, ByteSize(2<<20), config.Throttle.Download.Authenticated)
+	require.Equal(t, ByteSize(0), config.Throttle.Download.Admin)
+}
+
+func TestLoadFileError(t *testing.T) {
+	_, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+
+	_, err = LoadFile(writeConfigFile(t, "config.json", `{}`))
+	require.Error(t, err)
+
+	// Unknown keys are rejected
+	_, err = LoadFile(writeConfigFile(t, "config.yaml", "limit:\n  body_limit: 1MB\n"))
+	require.Error(t, err)
+	_, err = LoadFile(writeConfigFile(t, "config.toml", "[limit]\nbody_limit = \"1MB\"\n"))
+	require.Error(t, err)
+
+	_, err = LoadFile(writeConfigFile(t, "config.yaml", "timeout:\n  database: soon\n"))
+	require.Error(t, err)
+
+	setEnv(t, "RATE_LIMIT_MAX", "many")
+	_, err = LoadFile("")
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Default().Validate())
+
+	err := Default().Validate(RequireDatabase, RequireStorage, RequireEncryption, RequireServer)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "db.host (DB_HOST) is required")
+	require.Contains(t, err.Error(), "storage.path (STORAGE_PATH) is required")
+	require.Contains(t, err.Error(), "encryption.master_key (MASTER_KEY) is required")
+	require.Contains(t, err.Error(), "jwt_secret (JWT_SECRET) is required")
+
+	config := Default()
+	config.Port = "http"
+	config.Limits.MaxFileSize = 200 << 20
+	config.Limits.MaxArchiveFiles = 0
+	config.RateLimit.Max = 0
+	config.CORS.AllowOrigins = []string{"*"}
+	config.Tracing.SampleRatio = 2
+	config.DownloadEvent.Sink = "kafka"
+	config.Encryption.KeyProvider = "vault"
+	config.RateLimit.Store = "redis"
+	config.RateLimit.Auth.Window = 0
+	config.Throttle.Download.Global = -1
+	err = config.Validate(RequireEncryption)
+	require.Error(t, err)
+	for _, problem := range []string{
+		"port (PORT) must be between 1 and 65535",
+		"limits.max_file_size (MAX_FILE_SIZE) must not be larger than limits.body_limit (BODY_LIMIT)",
+		"limits.max_archive_files (MAX_ARCHIVE_FILES) must be positive",
+		"rate_limit.max (RATE_LIMIT_MAX) must be positive",
+		"cors.allow_origins (CORS_ALLOW_ORIGINS) cannot be *",
+		"tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1",
+		"download_event.sink (DOWNLOAD_EVENT_SINK) must be none, log or file",
+		"encryption.vault_address (VAULT_ADDR) is required",
+		"rate_limit.redis_url (RATE_LIMIT_REDIS_URL) is required",
+		"rate_limit.auth.window (RATE_LIMIT_AUTH_WINDOW) must be positive",
+		"throttle.download.global (THROTTLE_DOWNLOAD_GLOBAL) must not be negative",
+	} {
+		require.Contains(t, err.Error(), problem)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		size ByteSize
+	}{
+		{"1024", 1024},
+		{"10B", 10},
+		{"512KB", 512 << 10},
+		{"100MB", 100 << 20},
+		{"100 mb", 100 << 20},
+		{"1GiB", 1 << 30},
+		{"2G", 2 << 30},
+	} {
+		var size ByteSize
+		require.NoError(t, size.UnmarshalText([]byte(tc.text)), tc.text)
+		require.Equal(t, tc.size, size, tc.text)
+	}
+
+	for _, text := range []string{"", "MB", "1.5MB", "-1MB", "100TB", "99999999999GB"} {
+		var size ByteSize
+		require.Error(t, size.UnmarshalText([]byte(text)), text)
+	}
+}
+
+func TestEncryptionKeyProviderConfig(t *testing.T) {
+	cfg := Default()
+	cfg.Encryption.KeyProvider = "vault"
+	cfg.Encryption.OldMasterKeys = []string{"2021:old key"}
+	cfg.Encryption.VaultKeyName = "cscms"
+	keyProviderConfig, err := cfg.Encryption.KeyProviderConfig()
+	require.NoError(t, err)
+	require.Equal(t, "vault", keyProviderConfig.Provider)
+	require.Equal(t, map[string]string{"2021": "old key"}, keyProviderConfig.OldMasterKeys)
+	require.Equal(t, "cscms", keyProviderConfig.Vault.KeyName)
+	require.Equal(t, 10*time.Second, keyProviderConfig.Vault.Timeout)
+
+	cfg.Encryption.OldMasterKeys = []string{"no-key-id"}
+	_, err = cfg.Encryption.KeyProviderConfig()
+	require.Error(t, err)
+}
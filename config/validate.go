@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"github.com/thetkpark/cscms-temp-storage/service/ratelimit"
+	"strconv"
+	"strings"
+)
+
+// Requirement is the group of settings that the command cannot run without
+type Requirement int
+
+const (
+	// RequireDatabase requires the connection of the database
+	RequireDatabase Requirement = iota
+	// RequireStorage requires the path of the file storage
+	RequireStorage
+	// RequireEncryption requires the keys of the configured key provider
+	RequireEncryption
+	// RequireServer requires the settings that are only used by the server
+	RequireServer
+)
+
+// Validate checks the values and the required settings, all problems are reported together
+func (c *Config) Validate(required ...Requirement) error {
+	v := &validator{}
+	for _, requirement := range required {
+		switch requirement {
+		case RequireDatabase:
+			v.required("db.username (DB_USERNAME)", c.DB.Username)
+			v.required("db.host (DB_HOST)", c.DB.Host)
+			v.required("db.port (DB_PORT)", c.DB.Port)
+			v.required("db.database (DB_DATABASE)", c.DB.DatabaseName)
+		case RequireStorage:
+			v.required("storage.path (STORAGE_PATH)", c.Storage.Path)
+		case RequireEncryption:
+			c.validateEncryption(v)
+		case RequireServer:
+			v.required("port (PORT)", c.Port)
+			v.required("entrypoint (ENTRYPOINT)", c.Entrypoint)
+			v.required("jwt_secret (JWT_SECRET)", c.JWTSecret)
+			v.required("image_storage.connection_string (AZSTORAGE_CONNECTION_STRING)", c.ImageStorage.ConnectionString)
+			v.required("image_storage.container_name (AZSTORAGE_CONTAINER_NAME)", c.ImageStorage.ContainerName)
+			v.required("oauth.github_client_id (GITHUB_OAUTH_CLIENT_ID)", c.OAuth.GitHubClientID)
+			v.required("oauth.github_secret_key (GITHUB_OAUTH_SECRET_KEY)", c.OAuth.GitHubSecretKey)
+			v.required("oauth.google_client_id (GOOGLE_OAUTH_CLIENT_ID)", c.OAuth.GoogleClientID)
+			v.required("oauth.google_secret_key (GOOGLE_OAUTH_SECRET_KEY)", c.OAuth.GoogleSecretKey)
+		}
+	}
+
+	v.port("port (PORT)", c.Port)
+	v.port("db.port (DB_PORT)", c.DB.Port)
+	v.check(c.Storage.StoreDuration > 0, "storage.store_duration (STORE_DURATION) must be positive")
+
+	v.check(c.Timeout.Database > 0, "timeout.database (DB_TIMEOUT) must be positive")
+	v.check(c.Timeout.Storage > 0, "timeout.storage (STORAGE_TIMEOUT) must be positive")
+	v.check(c.Timeout.ImageStorage > 0, "timeout.image_storage (IMAGE_STORAGE_TIMEOUT) must be positive")
+	v.check(c.Timeout.RemoteFetch > 0, "timeout.remote_fetch (REMOTE_FETCH_TIMEOUT) must be positive")
+	v.check(c.Encryption.VaultTimeout > 0, "encryption.vault_timeout (VAULT_TIMEOUT) must be positive")
+
+	// The uploads are read by the server before the handlers, so they cannot be larger than the body limit
+	v.check(c.Limits.BodyLimit > 0, "limits.body_limit (BODY_LIMIT) must be positive")
+	v.uploadLimit("limits.max_file_size (MAX_FILE_SIZE)", c.Limits.MaxFileSize, c.Limits.BodyLimit)
+	v.uploadLimit("limits.max_bundle_size (MAX_BUNDLE_SIZE)", c.Limits.MaxBundleSize, c.Limits.BodyLimit)
+	v.uploadLimit("limits.max_paste_size (MAX_PASTE_SIZE)", c.Limits.MaxPasteSize, c.Limits.BodyLimit)
+	v.uploadLimit("limits.max_image_size (MAX_IMAGE_SIZE)", c.Limits.MaxImageSize, c.Limits.BodyLimit)
+	v.check(c.Limits.MaxRemoteFileSize > 0, "limits.max_remote_file_size (MAX_REMOTE_FILE_SIZE) must be positive")
+	v.check(c.Limits.MaxArchiveFiles > 0, "limits.max_archive_files (MAX_ARCHIVE_FILES) must be positive")
+
+	v.check(c.RateLimit.Max > 0, "rate_limit.max (RATE_LIMIT_MAX) must be positive")
+	v.check(c.RateLimit.Expiration > 0, "rate_limit.expiration (RATE_LIMIT_EXPIRATION) must be positive")
+	switch c.RateLimit.Store {
+	case ratelimit.StoreMemory, ratelimit.StoreDatabase:
+	case ratelimit.StoreRedis:
+		v.required("rate_limit.redis_url (RATE_LIMIT_REDIS_URL)", c.RateLimit.RedisURL)
+	default:
+		v.add(fmt.Sprintf("rate_limit.store (RATE_LIMIT_STORE) must be %s, %s or %s", ratelimit.StoreMemory, ratelimit.StoreDatabase, ratelimit.StoreRedis))
+	}
+	v.rateLimitRule("upload", c.RateLimit.Upload)
+	v.rateLimitRule("download", c.RateLimit.Download)
+	v.rateLimitRule("auth", c.RateLimit.Auth)
+	v.throttleRule("download", c.Throttle.Download)
+
+	v.check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins (CORS_ALLOW_ORIGINS) must have at least one origin")
+	for _, origin := range c.CORS.AllowOrigins {
+		// Credentials are allowed, so the browsers reject the wildcard origin
+		v.check(strings.TrimSpace(origin) != "*", "cors.allow_origins (CORS_ALLOW_ORIGINS) cannot be * because credentials are allowed")
+	}
+
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1")
+	switch c.DownloadEvent.Sink {
+	case event.SinkNone, event.SinkLog:
+	case event.SinkFile:
+		v.required("download_event.file (DOWNLOAD_EVENT_FILE)", c.DownloadEvent.File)
+	default:
+		v.add(fmt.Sprintf("download_event.sink (DOWNLOAD_EVENT_SINK) must be %s, %s or %s", event.SinkNone, event.SinkLog, event.SinkFile))
+	}
+
+	v.check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts (WEBHOOK_MAX_ATTEMPTS) must be positive")
+	v.check(c.Webhook.Backoff >= 0, "webhook.backoff (WEBHOOK_BACKOFF) must not be negative")
+	v.check(c.Webhook.Timeout > 0, "webhook.timeout (WEBHOOK_TIMEOUT) must be positive")
+	v.check(c.Webhook.ShutdownTimeout >= 0, "webhook.shutdown_timeout (WEBHOOK_SHUTDOWN_TIMEOUT) must not be negative")
+
+	v.check(c.Health.Timeout > 0, "health.timeout (HEALTH_CHECK_TIMEOUT) must be positive")
+	v.check(c.Health.MinFreeBytes >= 0, "health.min_free_bytes (HEALTH_MIN_FREE_BYTES) must not be negative")
+	v.check(c.Health.MinFreePercent >= 0 && c.Health.MinFreePercent <= 100, "health.min_free_percent (HEALTH_MIN_FREE_PERCENT) must be between 0 and 100")
+
+	v.check(c.Rekey.Delay >= 0, "rekey.delay (REKEY_DELAY) must not be negative")
+	v.check(c.Scrub.Delay >= 0, "scrub.delay (SCRUB_DELAY) must not be negative")
+
+	return v.err()
+}
+
+func (c *Config) validateEncryption(v *validator) {
+	switch c.Encryption.KeyProvider {
+	case encrypt.KeyProviderStatic:
+		v.required("encryption.master_key (MASTER_KEY)", c.Encryption.MasterKey)
+	case encrypt.KeyProviderFile:
+		v.required("encryption.key_file (KEY_FILE)", c.Encryption.KeyFile)
+	case encrypt.KeyProviderVault:
+		v.required("encryption.vault_address (VAULT_ADDR)", c.Encryption.VaultAddress)
+		v.required("encryption.vault_token (VAULT_TOKEN)", c.Encryption.VaultToken)
+		v.required("encryption.vault_transit_key (VAULT_TRANSIT_KEY)", c.Encryption.VaultKeyName)
+	default:
+		v.add(fmt.Sprintf("encryption.key_provider (KEY_PROVIDER) must be %s, %s or %s", encrypt.KeyProviderStatic, encrypt.KeyProviderFile, encrypt.KeyProviderVault))
+	}
+}
+
+// validator collects the problems of the config
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(problem string) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) check(ok bool, problem string) {
+	if !ok {
+		v.add(problem)
+	}
+}
+
+func (v *validator) required(name string, value string) {
+	v.check(len(strings.TrimSpace(value)) > 0, fmt.Sprintf("%s is required", name))
+}
+
+// port checks the port if it is set, the required check is separated
+func (v *validator) port(name string, value string) {
+	if len(value) == 0 {
+		return
+	}
+	port, err := strconv.Atoi(value)
+	v.check(err == nil && port > 0 && port <= 65535, fmt.Sprintf("%s must be between 1 and 65535", name))
+}
+
+func (v *validator) uploadLimit(name string, size ByteSize, bodyLimit ByteSize) {
+	v.check(size > 0, fmt.Sprintf("%s must be positive", name))
+	v.check(size <= bodyLimit, fmt.Sprintf("%s must not be larger than limits.body_limit (BODY_LIMIT)", name))
+}
+
+func (v *validator) rateLimitRule(name string, rule RateLimitRule) {
+	env := "RATE_LIMIT_" + strings.ToUpper(name) + "_"
+	v.check(rule.Max >= 0, fmt.Sprintf("rate_limit.%s.max (%sMAX) must not be negative", name, env))
+	v.check(rule.Bandwidth >= 0, fmt.Sprintf("rate_limit.%s.bandwidth (%sBANDWIDTH) must not be negative", name, env))
+	v.check(rule.Window > 0, fmt.Sprintf("rate_limit.%s.window (%sWINDOW) must be positive", name, env))
+}
+
+func (v *validator) throttleRule(name string, rule ThrottleRule) {
+	env := "THROTTLE_" + strings.ToUpper(name) + "_"
+	v.check(rule.Global >= 0, fmt.Sprintf("throttle.%s.global (%sGLOBAL) must not be negative", name, env))
+	v.check(rule.Anonymous >= 0, fmt.Sprintf("throttle.%s.anonymous (%sANONYMOUS) must not be negative", name, env))
+	v.check(rule.Authenticated >= 0, fmt.Sprintf("throttle.%s.authenticated (%sAUTHENTICATED) must not be negative", name, env))
+	v.check(rule.Admin >= 0, fmt.Sprintf("throttle.%s.admin (%sADMIN) must not be negative", name, env))
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config: %s", strings.Join(v.problems, "; "))
+}
@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AuditFilter selects the audit events, the zero value of a field does not filter
+type AuditFilter struct {
+	// UserID matches the events done by the user or on the content of the user
+	UserID uint
+	FileID string
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditDataStore only creates and finds the events, the events cannot be changed
+type AuditDataStore interface {
+	Create(ctx context.Context, event *model.AuditEvent) error
+	Find(ctx context.Context, filter AuditFilter) ([]model.AuditEvent, error)
+}
+
+type GormAuditDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormAuditDataStore(db *gorm.DB) (*GormAuditDataStore, error) {
+	if err := db.AutoMigrate(&model.AuditEvent{}); err != nil {
+		return nil, err
+	}
+	return &GormAuditDataStore{
+		db: db,
+	}, nil
+}
+
+func (store *GormAuditDataStore) Create(ctx context.Context, event *model.AuditEvent) error {
+	tx := store.db.WithContext(ctx).Create(event)
+	return tx.Error
+}
+
+// Find returns the events matching the filter from the newest
+func (store *GormAuditDataStore) Find(ctx context.Context, filter AuditFilter) ([]model.AuditEvent, error) {
+	tx := store.db.WithContext(ctx)
+	if filter.UserID != 0 {
+		tx = tx.Where("actor_id = ? OR owner_id = ?", filter.UserID, filter.UserID)
+	}
+	if len(filter.FileID) > 0 {
+		tx = tx.Where("file_id", filter.FileID)
+	}
+	if len(filter.Action) > 0 {
+		tx = tx.Where("action", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		tx = tx.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		tx = tx.Where("created_at < ?", filter.To)
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		tx = tx.Offset(filter.Offset)
+	}
+
+	var events []model.AuditEvent
+	tx = tx.Order("created_at DESC").Order("id DESC").Find(&events)
+	return events, tx.Error
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type GormAuditDataStoreTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	store  *GormAuditDataStore
+	events []*model.AuditEvent
+}
+
+func TestNewGormAuditDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	store, err := NewGormAuditDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.NoError(t, db.Create(createTestAuditEvent(model.AuditFileUpload, 1, 1, time.Now())).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormAuditDataStore(t *testing.T) {
+	suite.Run(t, new(GormAuditDataStoreTestSuite))
+}
+
+func (s *GormAuditDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.AuditEvent{}))
+
+	s.store = &GormAuditDataStore{db: gormDB}
+	now := time.Now().UTC()
+	s.events = []*model.AuditEvent{
+		createTestAuditEvent(model.AuditFileUpload, 1, 1, now.Add(-3*time.Hour)),
+		createTestAuditEvent(model.AuditFileDownload, 0, 1, now.Add(-2*time.Hour)),
+		createTestAuditEvent(model.AuditFileUpload, 2, 2, now.Add(-time.Hour)),
+		createTestAuditEvent(model.AuditAPIKeyGenerate, 1, 1, now),
+	}
+	s.events[1].FileID = s.events[0].FileID
+	s.events[3].FileID = nil
+	for _, event := range s.events {
+		require.NoError(s.T(), s.db.Create(event).Error)
+	}
+}
+
+func (s *GormAuditDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormAuditDataStoreTestSuite) TestCreate() {
+	event := createTestAuditEvent(model.AuditFileDelete, 1, 1, time.Now().UTC())
+	require.NoError(s.T(), s.store.Create(context.Background(), event))
+	require.NotZero(s.T(), event.ID)
+}
+
+func (s *GormAuditDataStoreTestSuite) TestImmutable() {
+	require.ErrorIs(s.T(), s.db.Model(s.events[0]).Update("action", model.AuditFileDelete).Error, model.ErrAuditEventImmutable)
+	require.ErrorIs(s.T(), s.db.Delete(s.events[0]).Error, model.ErrAuditEventImmutable)
+
+	var count int64
+	require.NoError(s.T(), s.db.Model(&model.AuditEvent{}).Where("action", model.AuditFileUpload).Count(&count).Error)
+	require.Equal(s.T(), int64(2), count)
+}
+
+func (s *GormAuditDataStoreTestSuite) TestFindByUser() {
+	// The download by anonymous user is on the content of the user
+	events, err := s.store.Find(context.Background(), AuditFilter{UserID: 1})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 3)
+	require.Equal(s.T(), s.events[3].ID, events[0].ID)
+	require.Equal(s.T(), s.events[0].ID, events[2].ID)
+}
+
+func (s *GormAuditDataStoreTestSuite) TestFindByFile() {
+	events, err := s.store.Find(context.Background(), AuditFilter{FileID: *s.events[0].FileID})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 2)
+
+	events, err = s.store.Find(context.Background(), AuditFilter{FileID: *s.events[0].FileID, Action: model.AuditFileDownload})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 1)
+	require.Equal(s.T(), s.events[1].ID, events[0].ID)
+}
+
+func (s *GormAuditDataStoreTestSuite) TestFindByTimeRange() {
+	events, err := s.store.Find(context.Background(), AuditFilter{From: s.events[1].CreatedAt, To: s.events[3].CreatedAt})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 2)
+	require.Equal(s.T(), s.events[2].ID, events[0].ID)
+	require.Equal(s.T(), s.events[1].ID, events[1].ID)
+}
+
+func (s *GormAuditDataStoreTestSuite) TestFindWithLimit() {
+	events, err := s.store.Find(context.Background(), AuditFilter{Limit: 2, Offset: 1})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 2)
+	require.Equal(s.T(), s.events[2].ID, events[0].ID)
+	require.Equal(s.T(), s.events[1].ID, events[1].ID)
+}
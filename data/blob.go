@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type BlobDataStore interface {
+	Create(ctx context.Context, blob *model.Blob) error
+	FindByID(ctx context.Context, blobID string) (*model.Blob, error)
+	FindByChecksum(ctx context.Context, userID uint, checksum string) (*model.Blob, error)
+	AddReference(ctx context.Context, blobID string) error
+	RemoveReference(ctx context.Context, blobID string) (bool, error)
+	DeleteByID(ctx context.Context, blobID string) error
+}
+
+type GormBlobDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormBlobDataStore(db *gorm.DB) (*GormBlobDataStore, error) {
+	if err := db.AutoMigrate(&model.Blob{}); err != nil {
+		return nil, err
+	}
+	return &GormBlobDataStore{
+		db: db,
+	}, nil
+}
+
+func (store *GormBlobDataStore) Create(ctx context.Context, blob *model.Blob) error {
+	tx := store.db.WithContext(ctx).Create(blob)
+	return tx.Error
+}
+
+func (store *GormBlobDataStore) FindByID(ctx context.Context, blobID string) (*model.Blob, error) {
+	var blob model.Blob
+	tx := store.db.WithContext(ctx).Where(&model.Blob{ID: blobID}).First(&blob)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &blob, tx.Error
+}
+
+func (store *GormBlobDataStore) FindByChecksum(ctx context.Context, userID uint, checksum string) (*model.Blob, error) {
+	var blob model.Blob
+	tx := store.db.WithContext(ctx).Where("user_id = ? AND checksum = ?", userID, checksum).First(&blob)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &blob, tx.Error
+}
+
+func (store *GormBlobDataStore) AddReference(ctx context.Context, blobID string) error {
+	tx := store.db.WithContext(ctx).Model(&model.Blob{}).Where("id", blobID).UpdateColumns(map[string]interface{}{
+		"ref_count":  gorm.Expr("ref_count + ?", 1),
+		"updated_at": time.Now().UTC(),
+	})
+	return tx.Error
+}
+
+// RemoveReference decreases the reference count of the blob.
+// The blob record is deleted and true is returned when the last reference is removed,
+// so the content can be deleted from storage.
+func (store *GormBlobDataStore) RemoveReference(ctx context.Context, blobID string) (bool, error) {
+	unreferenced := false
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Blob{}).Where("id = ? AND ref_count > 0", blobID).UpdateColumns(map[string]interface{}{
+			"ref_count":  gorm.Expr("ref_count - ?", 1),
+			"updated_at": time.Now().UTC(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var blob model.Blob
+		if err := tx.Where(&model.Blob{ID: blobID}).First(&blob).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if blob.RefCount > 0 {
+			return nil
+		}
+		unreferenced = true
+		return tx.Delete(&blob).Error
+	})
+	return unreferenced, err
+}
+
+func (store *GormBlobDataStore) DeleteByID(ctx context.Context, blobID string) error {
+	tx := store.db.WithContext(ctx).Delete(&model.Blob{ID: blobID})
+	return tx.Error
+}
+
+// ContentDeleter deletes the stored content of the file
+type ContentDeleter interface {
+	DeleteFile(ctx context.Context, fileName string) error
+}
+
+// ReleaseContent removes the reference of the file to its content.
+// The content is deleted from storage unless it is still shared with other files.
+func ReleaseContent(ctx context.Context, blobDataStore BlobDataStore, storage ContentDeleter, fileInfo *model.File) error {
+	if fileInfo.BlobID != nil {
+		unreferenced, err := blobDataStore.RemoveReference(ctx, *fileInfo.BlobID)
+		if err != nil || !unreferenced {
+			return err
+		}
+	}
+	return storage.DeleteFile(ctx, fileInfo.StorageKey())
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"context"
+	"github.com/go-test/deep"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type GormBlobDataStoreTestSuite struct {
+	suite.Suite
+	db    *gorm.DB
+	store *GormBlobDataStore
+	blob  *model.Blob
+}
+
+func TestNewGormBlobDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	store, err := NewGormBlobDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.NoError(t, db.Create(createTestBlob(1, 1)).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormBlobDataStore(t *testing.T) {
+	suite.Run(t, new(GormBlobDataStoreTestSuite))
+}
+
+func (s *GormBlobDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.Blob{}))
+
+	s.store = &GormBlobDataStore{db: gormDB}
+	s.blob = createTestBlob(1, 2)
+	require.NoError(s.T(), s.db.Create(s.blob).Error)
+}
+
+func (s *GormBlobDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormBlobDataStoreTestSuite) TestCreate() {
+	newBlob := createTestBlob(1, 1)
+	require.NoError(s.T(), s.store.Create(context.Background(), newBlob))
+
+	var queryBlob model.Blob
+	require.NoError(s.T(), s.db.Where("id", newBlob.ID).First(&queryBlob).Error)
+	require.Nil(s.T(), deep.Equal(&queryBlob, newBlob))
+}
+
+func (s *GormBlobDataStoreTestSuite) TestCreateDuplicatedChecksum() {
+	newBlob := createTestBlob(s.blob.UserID, 1)
+	newBlob.Checksum = s.blob.Checksum
+	require.Error(s.T(), s.store.Create(context.Background(), newBlob))
+
+	// The same content of another user is another blob
+	newBlob.UserID = s.blob.UserID + 1
+	require.NoError(s.T(), s.store.Create(context.Background(), newBlob))
+}
+
+func (s *GormBlobDataStoreTestSuite) TestFindByID() {
+	blob, err := s.store.FindByID(context.Background(), s.blob.ID)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), deep.Equal(blob, s.blob))
+
+	blob, err = s.store.FindByID(context.Background(), "notExist")
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), blob)
+}
+
+func (s *GormBlobDataStoreTestSuite) TestFindByChecksum() {
+	blob, err := s.store.FindByChecksum(context.Background(), s.blob.UserID, s.blob.Checksum)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), deep.Equal(blob, s.blob))
+
+	blob, err = s.store.FindByChecksum(context.Background(), s.blob.UserID+1, s.blob.Checksum)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), blob)
+}
+
+func (s *GormBlobDataStoreTestSuite) TestAddReference() {
+	require.NoError(s.T(), s.store.AddReference(context.Background(), s.blob.ID))
+
+	var queryBlob model.Blob
+	require.NoError(s.T(), s.db.Where("id", s.blob.ID).First(&queryBlob).Error)
+	require.Equal(s.T(), s.blob.RefCount+1, queryBlob.RefCount)
+}
+
+func (s *GormBlobDataStoreTestSuite) TestRemoveReference() {
+	unreferenced, err := s.store.RemoveReference(context.Background(), s.blob.ID)
+	require.NoError(s.T(), err)
+	require.False(s.T(), unreferenced)
+
+	unreferenced, err = s.store.RemoveReference(context.Background(), s.blob.ID)
+	require.NoError(s.T(), err)
+	require.True(s.T(), unreferenced)
+
+	var queryBlob model.Blob
+	require.ErrorIs(s.T(), s.db.Where("id", s.blob.ID).First(&queryBlob).Error, gorm.ErrRecordNotFound)
+
+	unreferenced, err = s.store.RemoveReference(context.Background(), s.blob.ID)
+	require.NoError(s.T(), err)
+	require.False(s.T(), unreferenced)
+}
+
+func (s *GormBlobDataStoreTestSuite) TestDeleteByID() {
+	require.NoError(s.T(), s.store.DeleteByID(context.Background(), s.blob.ID))
+	var queryBlob model.Blob
+	require.ErrorIs(s.T(), s.db.Where("id", s.blob.ID).First(&queryBlob).Error, gorm.ErrRecordNotFound)
+}
+
+type deletedFiles []string
+
+func (d *deletedFiles) DeleteFile(_ context.Context, fileName string) error {
+	*d = append(*d, fileName)
+	return nil
+}
+
+func (s *GormBlobDataStoreTestSuite) TestReleaseContent() {
+	var deleted deletedFiles
+	file := &model.File{ID: "file", BlobID: &s.blob.ID}
+
+	// The content is kept while another file references it
+	require.NoError(s.T(), ReleaseContent(context.Background(), s.store, &deleted, file))
+	require.Empty(s.T(), deleted)
+	require.NoError(s.T(), ReleaseContent(context.Background(), s.store, &deleted, file))
+	require.Equal(s.T(), deletedFiles{s.blob.ID}, deleted)
+
+	// The content that is not shared is deleted
+	deleted = nil
+	require.NoError(s.T(), ReleaseContent(context.Background(), s.store, &deleted, &model.File{ID: "other"}))
+	require.Equal(s.T(), deletedFiles{"other"}, deleted)
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type BundleDataStore interface {
+	Create(ctx context.Context, bundle *model.Bundle) error
+	FindByToken(ctx context.Context, token string) (*model.Bundle, error)
+	FindByUserID(ctx context.Context, userID uint) ([]model.Bundle, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type GormBundleDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormBundleDataStore(db *gorm.DB) (*GormBundleDataStore, error) {
+	if err := db.AutoMigrate(&model.Bundle{}); err != nil {
+		return nil, err
+	}
+	return &GormBundleDataStore{
+		db: db,
+	}, nil
+}
+
+// Create saves the bundle together with its files
+func (store *GormBundleDataStore) Create(ctx context.Context, bundle *model.Bundle) error {
+	tx := store.db.WithContext(ctx).Create(bundle)
+	return tx.Error
+}
+
+func (store *GormBundleDataStore) FindByToken(ctx context.Context, token string) (*model.Bundle, error) {
+	var bundles []*model.Bundle
+	if tx := store.db.WithContext(ctx).Preload("Files").Where(&model.Bundle{Token: token}).Find(&bundles); tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var bundle *model.Bundle
+	for _, v := range bundles {
+		if v.ExpiredAt.UTC().After(time.Now().UTC()) {
+			bundle = v
+			break
+		}
+	}
+
+	return bundle, nil
+}
+
+// FindByUserID finds the bundles of the user together with their files
+func (store *GormBundleDataStore) FindByUserID(ctx context.Context, userID uint) ([]model.Bundle, error) {
+	var bundles []model.Bundle
+	tx := store.db.WithContext(ctx).Preload("Files").Where(&model.Bundle{UserID: userID}).Find(&bundles)
+	return bundles, tx.Error
+}
+
+// DeleteExpired deletes the expired bundles and returns the number of deleted bundles.
+// The files of the bundles expire at the same time and are deleted from storage by the cleaner like the other files.
+func (store *GormBundleDataStore) DeleteExpired(ctx context.Context) (int64, error) {
+	tx := store.db.WithContext(ctx).Where("expired_at < ?", time.Now().UTC()).Delete(&model.Bundle{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type GormBundleDataStoreTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	store  *GormBundleDataStore
+	bundle *model.Bundle
+}
+
+func TestNewGormBundleDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.File{}))
+	store, err := NewGormBundleDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.NoError(t, db.Create(createTestBundle(0, false, 2)).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormBundleDataStore(t *testing.T) {
+	suite.Run(t, new(GormBundleDataStoreTestSuite))
+}
+
+func (s *GormBundleDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.File{}, &model.Bundle{}))
+
+	s.store = &GormBundleDataStore{db: gormDB}
+	s.bundle = createTestBundle(0, false, 3)
+	require.NoError(s.T(), s.db.Create(s.bundle).Error)
+}
+
+func (s *GormBundleDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormBundleDataStoreTestSuite) TestCreate() {
+	newBundle := createTestBundle(0, false, 2)
+	require.NoError(s.T(), s.store.Create(context.Background(), newBundle))
+
+	var files []model.File
+	require.NoError(s.T(), s.db.Where("bundle_id", newBundle.ID).Find(&files).Error)
+	require.Len(s.T(), files, 2)
+}
+
+func (s *GormBundleDataStoreTestSuite) TestFindByToken() {
+	bundle, err := s.store.FindByToken(context.Background(), s.bundle.Token)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), bundle)
+	require.Equal(s.T(), s.bundle.ID, bundle.ID)
+	require.Len(s.T(), bundle.Files, len(s.bundle.Files))
+}
+
+func (s *GormBundleDataStoreTestSuite) TestFindByTokenNotFound() {
+	bundle, err := s.store.FindByToken(context.Background(), createTestBundle(0, false, 0).Token)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), bundle)
+}
+
+func (s *GormBundleDataStoreTestSuite) TestFindByTokenExpired() {
+	newBundle := createTestBundle(0, true, 1)
+	require.NoError(s.T(), s.db.Create(newBundle).Error)
+
+	bundle, err := s.store.FindByToken(context.Background(), newBundle.Token)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), bundle)
+}
+
+func (s *GormBundleDataStoreTestSuite) TestFindByUserID() {
+	ownBundle := createTestBundle(7, false, 2)
+	require.NoError(s.T(), s.db.Create(ownBundle).Error)
+
+	bundles, err := s.store.FindByUserID(context.Background(), ownBundle.UserID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), bundles, 1)
+	require.Equal(s.T(), ownBundle.ID, bundles[0].ID)
+	require.Len(s.T(), bundles[0].Files, 2)
+}
+
+func (s *GormBundleDataStoreTestSuite) TestDeleteExpired() {
+	expiredBundle := createTestBundle(0, true, 1)
+	require.NoError(s.T(), s.db.Create(expiredBundle).Error)
+
+	deleted, err := s.store.DeleteExpired(context.Background())
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), deleted)
+
+	var count int64
+	require.NoError(s.T(), s.db.Model(&model.Bundle{}).Where("id", expiredBundle.ID).Count(&count).Error)
+	require.Equal(s.T(), int64(0), count)
+	require.NoError(s.T(), s.db.Model(&model.Bundle{}).Where("id", s.bundle.ID).Count(&count).Error)
+	require.Equal(s.T(), int64(1), count)
+}
@@ -0,0 +1,41 @@
+package data
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"time"
+)
+
+type DownloadDataStore interface {
+	Create(ctx context.Context, download *model.Download) error
+	FindByFileID(ctx context.Context, fileID string, from time.Time, to time.Time) ([]model.Download, error)
+}
+
+type GormDownloadDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormDownloadDataStore(db *gorm.DB) (*GormDownloadDataStore, error) {
+	if err := db.AutoMigrate(&model.Download{}); err != nil {
+		return nil, err
+	}
+	return &GormDownloadDataStore{
+		db: db,
+	}, nil
+}
+
+func (store *GormDownloadDataStore) Create(ctx context.Context, download *model.Download) error {
+	tx := store.db.WithContext(ctx).Create(download)
+	return tx.Error
+}
+
+// FindByFileID returns the downloads of the file from the time (inclusive) to the time (exclusive) from the oldest
+func (store *GormDownloadDataStore) FindByFileID(ctx context.Context, fileID string, from time.Time, to time.Time) ([]model.Download, error) {
+	var downloads []model.Download
+	tx := store.db.WithContext(ctx).
+		Where("file_id = ? AND created_at >= ? AND created_at < ?", fileID, from, to).
+		Order("created_at").
+		Find(&downloads)
+	return downloads, tx.Error
+}
@@ -0,0 +1,78 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type GormDownloadDataStoreTestSuite struct {
+	suite.Suite
+	db        *gorm.DB
+	store     *GormDownloadDataStore
+	now       time.Time
+	downloads []*model.Download
+}
+
+func TestNewGormDownloadDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	store, err := NewGormDownloadDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.NoError(t, db.Create(createTestDownload("file", time.Now())).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormDownloadDataStore(t *testing.T) {
+	suite.Run(t, new(GormDownloadDataStoreTestSuite))
+}
+
+func (s *GormDownloadDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.Download{}))
+
+	s.store = &GormDownloadDataStore{db: gormDB}
+	s.now = time.Now().UTC()
+	s.downloads = []*model.Download{
+		createTestDownload("file", s.now.Add(-2*time.Hour)),
+		createTestDownload("file", s.now.Add(-time.Hour)),
+		createTestDownload("other", s.now.Add(-time.Hour)),
+		createTestDownload("file", s.now),
+	}
+	for _, download := range s.downloads {
+		require.NoError(s.T(), s.db.Create(download).Error)
+	}
+}
+
+func (s *GormDownloadDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormDownloadDataStoreTestSuite) TestCreate() {
+	download := createTestDownload("file", s.now)
+	require.NoError(s.T(), s.store.Create(context.Background(), download))
+	require.NotZero(s.T(), download.ID)
+}
+
+func (s *GormDownloadDataStoreTestSuite) TestFindByFileID() {
+	downloads, err := s.store.FindByFileID(context.Background(), "file", s.now.Add(-2*time.Hour), s.now)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), downloads, 2)
+	require.Equal(s.T(), s.downloads[0].ID, downloads[0].ID)
+	require.Equal(s.T(), s.downloads[1].ID, downloads[1].ID)
+	require.Equal(s.T(), s.downloads[0].Bytes, downloads[0].Bytes)
+	require.Equal(s.T(), s.downloads[0].Referrer, downloads[0].Referrer)
+
+	downloads, err = s.store.FindByFileID(context.Background(), "missing", s.now.Add(-2*time.Hour), s.now)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), downloads, 0)
+}
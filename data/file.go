@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"github.com/thetkpark/cscms-temp-storage/data/model"
 	"gorm.io/gorm"
@@ -8,13 +9,23 @@ import (
 )
 
 type FileDataStore interface {
-	Create(file *model.File) error
-	FindByID(fileID string) (*model.File, error)
-	FindByToken(token string) (*model.File, error)
-	IncreaseVisited(id string) error
-	FindByUserID(userId uint) (*[]model.File, error)
-	DeleteByID(fileId string) error
-	UpdateToken(fileID string, newToken string) error
+	Create(ctx context.Context, file *model.File) error
+	FindByID(ctx context.Context, fileID string) (*model.File, error)
+	FindByStorageID(ctx context.Context, storageID string) (*model.File, error)
+	FindByToken(ctx context.Context, token string) (*model.File, error)
+	IncreaseVisited(ctx context.Context, id string) error
+	FindByUserID(ctx context.Context, userId uint) (*[]model.File, error)
+	FindByIDs(ctx context.Context, fileIDs []string) ([]model.File, error)
+	DeleteByID(ctx context.Context, fileId string) error
+	UpdateToken(ctx context.Context, fileID string, newToken string) error
+	UpdateExpiredAt(ctx context.Context, fileID string, expiredAt time.Time) error
+	FindUnencrypted(ctx context.Context) ([]model.File, error)
+	FindToRekey(ctx context.Context, keyIDs []string) ([]model.File, error)
+	FindWithChecksum(ctx context.Context) ([]model.File, error)
+	FindByBlobID(ctx context.Context, blobID string) (*model.File, error)
+	FindAllByBlobID(ctx context.Context, blobID string) ([]model.File, error)
+	CountActiveByBlobID(ctx context.Context, blobID string) (int64, error)
+	UpdateEncryption(ctx context.Context, file *model.File, replaceFile func() error) error
 }
 
 type GormFileDataStore struct {
@@ -33,23 +44,33 @@ func NewGormFileDataStore(db *gorm.DB, duration time.Duration) (*GormFileDataSto
 	}, nil
 }
 
-func (store *GormFileDataStore) Create(file *model.File) error {
-	tx := store.db.Create(file)
+func (store *GormFileDataStore) Create(ctx context.Context, file *model.File) error {
+	tx := store.db.WithContext(ctx).Create(file)
 	return tx.Error
 }
 
-func (store *GormFileDataStore) FindByID(fileID string) (*model.File, error) {
+func (store *GormFileDataStore) FindByID(ctx context.Context, fileID string) (*model.File, error) {
 	var file model.File
-	tx := store.db.Where(&model.File{ID: fileID}).First(&file)
+	tx := store.db.WithContext(ctx).Where(&model.File{ID: fileID}).First(&file)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	return &file, tx.Error
 }
 
-func (store *GormFileDataStore) FindByToken(token string) (*model.File, error) {
+// FindByStorageID finds the file whose own content is stored under the name, the content of blobs is not included
+func (store *GormFileDataStore) FindByStorageID(ctx context.Context, storageID string) (*model.File, error) {
+	var file model.File
+	tx := store.db.WithContext(ctx).Where("storage_id = ? OR (id = ? AND storage_id IS NULL)", storageID, storageID).First(&file)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &file, tx.Error
+}
+
+func (store *GormFileDataStore) FindByToken(ctx context.Context, token string) (*model.File, error) {
 	var files []*model.File
-	if tx := store.db.Where(&model.File{Token: token}).Find(&files); tx.Error != nil {
+	if tx := store.db.WithContext(ctx).Where(&model.File{Token: token}).Find(&files); tx.Error != nil {
 		return nil, tx.Error
 	}
 
@@ -64,26 +85,105 @@ func (store *GormFileDataStore) FindByToken(token string) (*model.File, error) {
 	return file, nil
 }
 
-func (store *GormFileDataStore) FindByUserID(userId uint) (*[]model.File, error) {
+// FindByUserID finds the files of the user, the files of the bundles are found with their bundle
+func (store *GormFileDataStore) FindByUserID(ctx context.Context, userId uint) (*[]model.File, error) {
 	var files []model.File
-	tx := store.db.Where(&model.File{UserID: userId}).Find(&files)
+	tx := store.db.WithContext(ctx).Where(&model.File{UserID: userId}).Where("bundle_id IS NULL").Find(&files)
 	return &files, tx.Error
 }
 
-func (store *GormFileDataStore) DeleteByID(fileId string) error {
-	tx := store.db.Delete(&model.File{ID: fileId})
+func (store *GormFileDataStore) FindByIDs(ctx context.Context, fileIDs []string) ([]model.File, error) {
+	var files []model.File
+	tx := store.db.WithContext(ctx).Where("id IN ?", fileIDs).Find(&files)
+	return files, tx.Error
+}
+
+func (store *GormFileDataStore) DeleteByID(ctx context.Context, fileId string) error {
+	tx := store.db.WithContext(ctx).Delete(&model.File{ID: fileId})
 	return tx.Error
 }
 
-func (store *GormFileDataStore) IncreaseVisited(id string) error {
-	tx := store.db.Table("files").Where(&model.File{ID: id}).UpdateColumns(map[string]interface{}{
+func (store *GormFileDataStore) IncreaseVisited(ctx context.Context, id string) error {
+	tx := store.db.WithContext(ctx).Table("files").Where(&model.File{ID: id}).UpdateColumns(map[string]interface{}{
 		"visited":    gorm.Expr("visited + ?", 1),
 		"updated_at": time.Now().UTC(),
 	})
 	return tx.Error
 }
 
-func (store *GormFileDataStore) UpdateToken(fileID string, newToken string) error {
-	tx := store.db.Model(&model.File{}).Where("id", fileID).UpdateColumn("token", newToken)
+func (store *GormFileDataStore) UpdateToken(ctx context.Context, fileID string, newToken string) error {
+	tx := store.db.WithContext(ctx).Model(&model.File{}).Where("id", fileID).UpdateColumn("token", newToken)
 	return tx.Error
 }
+
+func (store *GormFileDataStore) UpdateExpiredAt(ctx context.Context, fileID string, expiredAt time.Time) error {
+	tx := store.db.WithContext(ctx).Model(&model.File{}).Where("id", fileID).UpdateColumn("expired_at", expiredAt)
+	return tx.Error
+}
+
+func (store *GormFileDataStore) FindUnencrypted(ctx context.Context) ([]model.File, error) {
+	var files []model.File
+	tx := store.db.WithContext(ctx).Where("encrypted", false).Find(&files)
+	return files, tx.Error
+}
+
+// FindToRekey finds the encrypted files whose key ID is not in keyIDs or that have no wrapped data key.
+// The rows created before the key columns were added hold NULL, which is neither in nor not in keyIDs.
+func (store *GormFileDataStore) FindToRekey(ctx context.Context, keyIDs []string) ([]model.File, error) {
+	var files []model.File
+	tx := store.db.WithContext(ctx).Where("encrypted", true).
+		Where("key_id IS NULL OR key_id NOT IN ? OR wrapped_key IS NULL OR wrapped_key = ?", keyIDs, "").
+		Find(&files)
+	return files, tx.Error
+}
+
+// FindWithChecksum finds the files that have checksum recorded
+func (store *GormFileDataStore) FindWithChecksum(ctx context.Context) ([]model.File, error) {
+	var files []model.File
+	tx := store.db.WithContext(ctx).Where("checksum <> ?", "").Find(&files)
+	return files, tx.Error
+}
+
+// FindByBlobID finds one of the files that share the content of the blob
+func (store *GormFileDataStore) FindByBlobID(ctx context.Context, blobID string) (*model.File, error) {
+	var file model.File
+	tx := store.db.WithContext(ctx).Where("blob_id", blobID).First(&file)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &file, tx.Error
+}
+
+// FindAllByBlobID finds all the files that share the content of the blob
+func (store *GormFileDataStore) FindAllByBlobID(ctx context.Context, blobID string) ([]model.File, error) {
+	var files []model.File
+	tx := store.db.WithContext(ctx).Where("blob_id = ?", blobID).Find(&files)
+	return files, tx.Error
+}
+
+// CountActiveByBlobID counts the files that are not expired and share the content of the blob
+func (store *GormFileDataStore) CountActiveByBlobID(ctx context.Context, blobID string) (int64, error) {
+	var count int64
+	tx := store.db.WithContext(ctx).Model(&model.File{}).Where("blob_id = ? AND expired_at > ?", blobID, time.Now().UTC()).Count(&count)
+	return count, tx.Error
+}
+
+// UpdateEncryption marks the file as encrypted with its nonce, key ID and wrapped data key and points it to its content.
+// replaceFile is called inside the transaction, so the record is only updated if the content is moved successfully.
+// The content must be written under a new storage ID, so the old content is still readable if the transaction fails.
+func (store *GormFileDataStore) UpdateEncryption(ctx context.Context, file *model.File, replaceFile func() error) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.File{}).Where("id", file.ID).UpdateColumns(map[string]interface{}{
+			"encrypted":   true,
+			"nonce":       file.Nonce,
+			"key_id":      file.KeyID,
+			"wrapped_key": file.WrappedKey,
+			"storage_id":  file.StorageID,
+			"blob_id":     file.BlobID,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceFile()
+	})
+}
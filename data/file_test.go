@@ -1,6 +1,8 @@
 package data
 
 import (
+	"context"
+	"errors"
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -62,7 +64,7 @@ func (s *GormFileDataStoreTestSuite) AfterTest(_, _ string) {
 
 func (s *GormFileDataStoreTestSuite) TestCreate() {
 	newFile := createTestFile(0, false)
-	require.NoError(s.T(), s.store.Create(newFile))
+	require.NoError(s.T(), s.store.Create(context.Background(), newFile))
 
 	var queryFile model.File
 	require.NoError(s.T(), s.db.Where(newFile).First(&queryFile).Error)
@@ -70,27 +72,27 @@ func (s *GormFileDataStoreTestSuite) TestCreate() {
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByID() {
-	file, err := s.store.FindByID(s.file.ID)
+	file, err := s.store.FindByID(context.Background(), s.file.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(file, s.file))
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByIDNotFound() {
 	newFile := createTestFile(0, false)
-	file, err := s.store.FindByID(newFile.ID)
+	file, err := s.store.FindByID(context.Background(), newFile.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), file)
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByToken() {
-	file, err := s.store.FindByToken(s.file.Token)
+	file, err := s.store.FindByToken(context.Background(), s.file.Token)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(file, s.file))
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByTokenNotFound() {
 	newFile := createTestFile(0, false)
-	file, err := s.store.FindByToken(newFile.Token)
+	file, err := s.store.FindByToken(context.Background(), newFile.Token)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), file)
 }
@@ -99,26 +101,41 @@ func (s *GormFileDataStoreTestSuite) TestFindByTokenExpired() {
 	newFile := createTestFile(0, true)
 	s.db.Create(newFile)
 
-	file, err := s.store.FindByToken(newFile.Token)
+	file, err := s.store.FindByToken(context.Background(), newFile.Token)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), file)
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByUserID() {
-	files, err := s.store.FindByUserID(s.user.ID)
+	files, err := s.store.FindByUserID(context.Background(), s.user.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), *files, len(s.ownFiles))
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindByUserIDWithoutBundleFiles() {
+	require.NoError(s.T(), s.db.AutoMigrate(&model.Bundle{}))
+	require.NoError(s.T(), s.db.Create(createTestBundle(s.user.ID, false, 2)).Error)
+
+	files, err := s.store.FindByUserID(context.Background(), s.user.ID)
 	require.NoError(s.T(), err)
 	require.Len(s.T(), *files, len(s.ownFiles))
 }
 
 func (s *GormFileDataStoreTestSuite) TestFindByUserIDEmpty() {
 	newUser := createTestUser("google")
-	files, err := s.store.FindByUserID(newUser.ID)
+	files, err := s.store.FindByUserID(context.Background(), newUser.ID)
 	require.NoError(s.T(), err)
 	require.Len(s.T(), *files, 0)
 }
 
+func (s *GormFileDataStoreTestSuite) TestFindByIDs() {
+	files, err := s.store.FindByIDs(context.Background(), []string{s.ownFiles[0].ID, s.ownFiles[1].ID, createTestFile(0, false).ID})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 2)
+}
+
 func (s *GormFileDataStoreTestSuite) TestIncreaseVisited() {
-	require.NoError(s.T(), s.store.IncreaseVisited(s.file.ID))
+	require.NoError(s.T(), s.store.IncreaseVisited(context.Background(), s.file.ID))
 
 	var queryFile model.File
 	require.NoError(s.T(), s.db.Where("id", s.file.ID).First(&queryFile).Error)
@@ -126,15 +143,161 @@ func (s *GormFileDataStoreTestSuite) TestIncreaseVisited() {
 }
 
 func (s *GormFileDataStoreTestSuite) TestDeleteByID() {
-	require.NoError(s.T(), s.store.DeleteByID(s.file.ID))
+	require.NoError(s.T(), s.store.DeleteByID(context.Background(), s.file.ID))
 	var queryFile model.File
 	require.ErrorIs(s.T(), s.db.Where("id", s.file.ID).First(&queryFile).Error, gorm.ErrRecordNotFound)
 }
 
 func (s *GormFileDataStoreTestSuite) TestUpdateToken() {
 	s.file.Token = "newToken"
-	require.NoError(s.T(), s.store.UpdateToken(s.file.ID, s.file.Token))
+	require.NoError(s.T(), s.store.UpdateToken(context.Background(), s.file.ID, s.file.Token))
 	var queryFile model.File
 	require.NoError(s.T(), s.db.Where("token", s.file.Token).First(&queryFile).Error)
 	require.Nil(s.T(), deep.Equal(&queryFile, s.file))
 }
+
+func (s *GormFileDataStoreTestSuite) TestUpdateExpiredAt() {
+	expiredAt := time.Now().Add(48 * time.Hour)
+	require.NoError(s.T(), s.store.UpdateExpiredAt(context.Background(), s.file.ID, expiredAt))
+	var queryFile model.File
+	require.NoError(s.T(), s.db.Where("id", s.file.ID).First(&queryFile).Error)
+	require.True(s.T(), queryFile.ExpiredAt.Equal(expiredAt))
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindUnencrypted() {
+	require.NoError(s.T(), s.db.Model(&model.File{}).Where("1 = 1").UpdateColumn("encrypted", true).Error)
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("encrypted", false).Error)
+
+	files, err := s.store.FindUnencrypted(context.Background())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 1)
+	require.Equal(s.T(), s.file.ID, files[0].ID)
+}
+
+func (s *GormFileDataStoreTestSuite) TestUpdateEncryption() {
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("encrypted", false).Error)
+	replaced := false
+	s.file.Nonce = "newNonce"
+	s.file.KeyID = "newKey"
+	s.file.WrappedKey = "newWrappedKey"
+	storageID := s.file.ID + ".new"
+	s.file.StorageID = &storageID
+	require.NoError(s.T(), s.store.UpdateEncryption(context.Background(), s.file, func() error {
+		replaced = true
+		return nil
+	}))
+	require.True(s.T(), replaced)
+
+	var queryFile model.File
+	require.NoError(s.T(), s.db.Where("id", s.file.ID).First(&queryFile).Error)
+	require.True(s.T(), queryFile.Encrypted)
+	require.Equal(s.T(), "newNonce", queryFile.Nonce)
+	require.Equal(s.T(), "newKey", queryFile.KeyID)
+	require.Equal(s.T(), "newWrappedKey", queryFile.WrappedKey)
+	require.Equal(s.T(), storageID, queryFile.StorageKey())
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindByStorageID() {
+	file, err := s.store.FindByStorageID(context.Background(), s.file.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), s.file.ID, file.ID)
+
+	// The file is only found by its new content after the content is rewritten
+	storageID := s.file.ID + ".new"
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("storage_id", storageID).Error)
+	file, err = s.store.FindByStorageID(context.Background(), s.file.ID)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), file)
+	file, err = s.store.FindByStorageID(context.Background(), storageID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), s.file.ID, file.ID)
+}
+
+func (s *GormFileDataStoreTestSuite) TestUpdateEncryptionRollback() {
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("encrypted", false).Error)
+	err := s.store.UpdateEncryption(context.Background(), &model.File{ID: s.file.ID, Nonce: "newNonce"}, func() error {
+		return errors.New("unable to replace file")
+	})
+	require.Error(s.T(), err)
+
+	var queryFile model.File
+	require.NoError(s.T(), s.db.Where("id", s.file.ID).First(&queryFile).Error)
+	require.False(s.T(), queryFile.Encrypted)
+	require.Equal(s.T(), s.file.Nonce, queryFile.Nonce)
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindToRekey() {
+	require.NoError(s.T(), s.db.Model(&model.File{}).Where("1 = 1").UpdateColumns(map[string]interface{}{"encrypted": true, "key_id": "current", "wrapped_key": "wrapped"}).Error)
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("key_id", "old").Error)
+	require.NoError(s.T(), s.db.Model(&s.ownFiles[0]).UpdateColumn("wrapped_key", "").Error)
+	require.NoError(s.T(), s.db.Model(&s.ownFiles[1]).UpdateColumns(map[string]interface{}{"encrypted": false, "key_id": "", "wrapped_key": ""}).Error)
+
+	files, err := s.store.FindToRekey(context.Background(), []string{"current"})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 2)
+
+	files, err = s.store.FindToRekey(context.Background(), []string{"current", "old"})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 1)
+	require.Equal(s.T(), s.ownFiles[0].ID, files[0].ID)
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindToRekeyNullKey() {
+	require.NoError(s.T(), s.db.Model(&model.File{}).Where("1 = 1").UpdateColumns(map[string]interface{}{"encrypted": true, "key_id": "current", "wrapped_key": "wrapped"}).Error)
+	// The legacy rows have NULL in the columns added by the migration
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumns(map[string]interface{}{"key_id": gorm.Expr("NULL"), "wrapped_key": gorm.Expr("NULL")}).Error)
+	require.NoError(s.T(), s.db.Model(&s.ownFiles[0]).UpdateColumn("wrapped_key", gorm.Expr("NULL")).Error)
+
+	files, err := s.store.FindToRekey(context.Background(), []string{"current"})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 2)
+	require.ElementsMatch(s.T(), []string{s.file.ID, s.ownFiles[0].ID}, []string{files[0].ID, files[1].ID})
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindWithChecksum() {
+	require.NoError(s.T(), s.db.Model(s.file).UpdateColumn("checksum", "checksum").Error)
+	files, err := s.store.FindWithChecksum(context.Background())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, 1)
+	require.Equal(s.T(), s.file.ID, files[0].ID)
+}
+
+func (s *GormFileDataStoreTestSuite) TestCountActiveByBlobID() {
+	blobID := s.ownFiles[0].ID
+	for i := range s.ownFiles {
+		require.NoError(s.T(), s.db.Model(&s.ownFiles[i]).UpdateColumn("blob_id", blobID).Error)
+	}
+	count, err := s.store.CountActiveByBlobID(context.Background(), blobID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(2), count)
+
+	count, err = s.store.CountActiveByBlobID(context.Background(), s.file.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(0), count)
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindAllByBlobID() {
+	blobID := s.ownFiles[0].ID
+	for i := range s.ownFiles {
+		require.NoError(s.T(), s.db.Model(&s.ownFiles[i]).UpdateColumn("blob_id", blobID).Error)
+	}
+	files, err := s.store.FindAllByBlobID(context.Background(), blobID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), files, len(s.ownFiles))
+
+	files, err = s.store.FindAllByBlobID(context.Background(), s.file.ID)
+	require.NoError(s.T(), err)
+	require.Empty(s.T(), files)
+}
+
+func (s *GormFileDataStoreTestSuite) TestFindByBlobID() {
+	blobID := s.ownFiles[0].ID
+	require.NoError(s.T(), s.db.Model(&s.ownFiles[1]).UpdateColumn("blob_id", blobID).Error)
+	file, err := s.store.FindByBlobID(context.Background(), blobID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), s.ownFiles[1].ID, file.ID)
+
+	file, err = s.store.FindByBlobID(context.Background(), s.file.ID)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), file)
+}
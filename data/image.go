@@ -1,16 +1,17 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"github.com/thetkpark/cscms-temp-storage/data/model"
 	"gorm.io/gorm"
 )
 
 type ImageDataStore interface {
-	Create(image *model.Image) error
-	FindByUserID(userID uint) (*[]model.Image, error)
-	FindByID(imageID uint) (*model.Image, error)
-	DeleteByID(imageId uint) error
+	Create(ctx context.Context, image *model.Image) error
+	FindByUserID(ctx context.Context, userID uint) (*[]model.Image, error)
+	FindByID(ctx context.Context, imageID uint) (*model.Image, error)
+	DeleteByID(ctx context.Context, imageId uint) error
 }
 
 type GormImageDataStore struct {
@@ -26,27 +27,27 @@ func NewGormImageDataStore(db *gorm.DB) (*GormImageDataStore, error) {
 	}, nil
 }
 
-func (g *GormImageDataStore) Create(image *model.Image) error {
-	tx := g.db.Create(image)
+func (g *GormImageDataStore) Create(ctx context.Context, image *model.Image) error {
+	tx := g.db.WithContext(ctx).Create(image)
 	return tx.Error
 }
 
-func (g *GormImageDataStore) DeleteByID(imageId uint) error {
-	tx := g.db.Delete(&model.Image{}, imageId)
+func (g *GormImageDataStore) DeleteByID(ctx context.Context, imageId uint) error {
+	tx := g.db.WithContext(ctx).Delete(&model.Image{}, imageId)
 	return tx.Error
 }
 
-func (g *GormImageDataStore) FindByID(imageID uint) (*model.Image, error) {
+func (g *GormImageDataStore) FindByID(ctx context.Context, imageID uint) (*model.Image, error) {
 	var image model.Image
-	tx := g.db.Where(&model.User{ID: imageID}).First(&image)
+	tx := g.db.WithContext(ctx).Where(&model.User{ID: imageID}).First(&image)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	return &image, tx.Error
 }
 
-func (g *GormImageDataStore) FindByUserID(userID uint) (*[]model.Image, error) {
+func (g *GormImageDataStore) FindByUserID(ctx context.Context, userID uint) (*[]model.Image, error) {
 	var images []model.Image
-	tx := g.db.Where(&model.Image{UserID: userID}).Find(&images)
+	tx := g.db.WithContext(ctx).Where(&model.Image{UserID: userID}).Find(&images)
 	return &images, tx.Error
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -61,7 +62,7 @@ func (s *GormImageDataStoreTestSuite) AfterTest(_, _ string) {
 
 func (s *GormImageDataStoreTestSuite) TestCreate() {
 	newImage := createTestImage(0)
-	require.NoError(s.T(), s.store.Create(newImage))
+	require.NoError(s.T(), s.store.Create(context.Background(), newImage))
 
 	var queryImage model.Image
 	require.NoError(s.T(), s.db.Where(newImage).First(&queryImage).Error)
@@ -69,33 +70,33 @@ func (s *GormImageDataStoreTestSuite) TestCreate() {
 }
 
 func (s *GormImageDataStoreTestSuite) TestFindByID() {
-	queryImage, err := s.store.FindByID(s.image.ID)
+	queryImage, err := s.store.FindByID(context.Background(), s.image.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(queryImage, s.image))
 }
 
 func (s *GormImageDataStoreTestSuite) TestFindByIDNotFound() {
 	newImg := createTestImage(0)
-	queryImage, err := s.store.FindByID(newImg.ID)
+	queryImage, err := s.store.FindByID(context.Background(), newImg.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), queryImage)
 }
 
 func (s *GormImageDataStoreTestSuite) TestFindByUserID() {
-	images, err := s.store.FindByUserID(s.user.ID)
+	images, err := s.store.FindByUserID(context.Background(), s.user.ID)
 	require.NoError(s.T(), err)
 	require.Len(s.T(), *images, len(s.ownImages))
 }
 
 func (s *GormImageDataStoreTestSuite) TestFindByUserIDEmpty() {
 	newUser := createTestUser("google")
-	images, err := s.store.FindByUserID(newUser.ID)
+	images, err := s.store.FindByUserID(context.Background(), newUser.ID)
 	require.NoError(s.T(), err)
 	require.Len(s.T(), *images, 0)
 }
 
 func (s *GormImageDataStoreTestSuite) TestDeleteByID() {
-	require.NoError(s.T(), s.store.DeleteByID(s.image.ID))
+	require.NoError(s.T(), s.store.DeleteByID(context.Background(), s.image.ID))
 
 	var queryImage model.Image
 	require.ErrorIs(s.T(), s.db.Where(s.image).First(&queryImage).Error, gorm.ErrRecordNotFound)
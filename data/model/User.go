@@ -2,6 +2,11 @@ package model
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID        uint      `gorm:"primaryKey,autoIncrement" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
@@ -13,4 +18,5 @@ type User struct {
 	Files     []File    `json:"files"`
 	Images    []Image   `json:"images"`
 	APIKey    string    `json:"api_key" gorm:"index"`
+	Role      string    `json:"role" gorm:"default:user"`
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	AuditFileUpload     = "file.upload"
+	AuditFileDownload   = "file.download"
+	AuditFileTokenEdit  = "file.token_edit"
+	AuditFileExpiryEdit = "file.expiry_edit"
+	AuditFileDelete     = "file.delete"
+	AuditImageUpload    = "image.upload"
+	AuditImageDelete    = "image.delete"
+	AuditAPIKeyGenerate = "user.api_key_generate"
+)
+
+// ErrAuditEventImmutable is returned when an audit event is updated or deleted
+var ErrAuditEventImmutable = errors.New("audit event cannot be changed")
+
+// AuditEvent is the record of an action on the content of a user.
+// ActorID is the user that does the action and OwnerID is the owner of the content, 0 for anonymous user.
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey,autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	Action    string    `gorm:"index" json:"action"`
+	ActorID   uint      `gorm:"index" json:"actor_id"`
+	OwnerID   uint      `gorm:"index" json:"owner_id"`
+	FileID    *string   `gorm:"index" json:"file_id,omitempty"`
+	ImageID   *uint     `gorm:"index" json:"image_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	RequestID string    `json:"request_id"`
+	IP        string    `json:"ip"`
+}
+
+func (e *AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+func (e *AuditEvent) BeforeDelete(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
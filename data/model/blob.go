@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Blob is the stored content shared by the files with the same content of the same user
+type Blob struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `gorm:"uniqueIndex:idx_blob_user_checksum" json:"user_id"`
+	Checksum  string    `gorm:"uniqueIndex:idx_blob_user_checksum;size:64" json:"checksum"`
+	Size      uint64    `json:"size"`
+	RefCount  uint      `json:"ref_count"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type Bundle struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+	Token     string    `gorm:"index" json:"token"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Files     []File    `json:"files"`
+	DeletedAt gorm.DeletedAt
+}
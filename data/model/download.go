@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Download is the record of a single download of the file.
+// The download is completed only when the whole content is sent to the client.
+type Download struct {
+	ID              uint      `gorm:"primaryKey,autoIncrement" json:"id"`
+	CreatedAt       time.Time `gorm:"index:idx_download_file_created,priority:2" json:"created_at"`
+	FileID          string    `gorm:"index:idx_download_file_created,priority:1" json:"file_id"`
+	Completed       bool      `json:"completed"`
+	Bytes           uint64    `json:"bytes"`
+	Referrer        string    `json:"referrer"`
+	UserAgentFamily string    `json:"user_agent_family"`
+}
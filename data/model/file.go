@@ -5,18 +5,43 @@ import (
 	"time"
 )
 
+// PermanentExpiry is the ExpiredAt of files that are kept forever
+var PermanentExpiry = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
 type File struct {
-	ID        string    `gorm:"primaryKey" json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	ExpiredAt time.Time `json:"expired_at"`
-	Token     string    `gorm:"index" json:"token"`
-	Nonce     string    `json:"nonce"`
-	Filename  string    `json:"filename"`
-	FileSize  uint64    `json:"file_size"`
-	Visited   uint      `json:"visited"`
-	UserID    uint      `gorm:"index"`
-	FileType  string    `json:"file_type"`
-	Encrypted bool      `json:"encrypted"`
-	DeletedAt gorm.DeletedAt
+	ID              string    `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	ExpiredAt       time.Time `json:"expired_at"`
+	Token           string    `gorm:"index" json:"token"`
+	Nonce           string    `json:"nonce"`
+	KeyID           string    `gorm:"index" json:"key_id"`
+	WrappedKey      string    `gorm:"type:text" json:"-"`
+	Filename        string    `json:"filename"`
+	FileSize        uint64    `json:"file_size"`
+	Visited         uint      `json:"visited"`
+	UserID          uint      `gorm:"index"`
+	FileType        string    `json:"file_type"`
+	Encrypted       bool      `json:"encrypted"`
+	ClientEncrypted bool      `json:"client_encrypted"`
+	Checksum        string    `json:"checksum"`
+	BundleID        *string   `gorm:"index" json:"bundle_id,omitempty"`
+	BlobID          *string   `gorm:"index" json:"-"`
+	StorageID       *string   `gorm:"index" json:"-"`
+	IsPaste         bool      `json:"is_paste"`
+	Language        string    `json:"language,omitempty"`
+	DeletedAt       gorm.DeletedAt
+}
+
+// StorageKey is the name of the file content on storage.
+// Deduplicated files share the content of the blob,
+// the content rewritten by the encrypt and rekey commands is stored under the storage ID.
+func (f *File) StorageKey() string {
+	if f.BlobID != nil {
+		return *f.BlobID
+	}
+	if f.StorageID != nil {
+		return *f.StorageID
+	}
+	return f.ID
 }
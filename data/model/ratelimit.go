@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// RateLimitCounter is the number of hits of the rate limit key in the window.
+// The ID already contains the start of the window, so the counter is only incremented until it expires.
+type RateLimitCounter struct {
+	ID        string    `gorm:"primaryKey;size:191"`
+	Hits      int64     `gorm:"not null"`
+	ExpiredAt time.Time `gorm:"index"`
+}
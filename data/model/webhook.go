@@ -0,0 +1,43 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook is the subscription of the user to the events of its files and images
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey,autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	URL       string    `json:"url"`
+	// Secret signs the payloads, it is only returned when the webhook is created
+	Secret string `json:"-"`
+	// Events is the comma separated event types
+	Events string `json:"events"`
+}
+
+// Subscribed reports whether the webhook receives the event type
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, event := range strings.Split(w.Events, ",") {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is the record of a single attempt to deliver the event to the webhook
+type WebhookDelivery struct {
+	ID         uint      `gorm:"primaryKey,autoIncrement" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	WebhookID  uint      `gorm:"index" json:"webhook_id"`
+	EventID    string    `json:"event_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type RateLimitDataStore interface {
+	// Increment adds n to the hits of the key and returns the new hits
+	Increment(ctx context.Context, key string, n int64, expiredAt time.Time) (int64, error)
+	// DeleteExpired deletes the counters of the past windows and returns the number of deleted counters
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type GormRateLimitDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormRateLimitDataStore(db *gorm.DB) (*GormRateLimitDataStore, error) {
+	if err := db.AutoMigrate(&model.RateLimitCounter{}); err != nil {
+		return nil, err
+	}
+	return &GormRateLimitDataStore{
+		db: db,
+	}, nil
+}
+
+// Increment upserts the counter, so the replicas can increment the same key concurrently
+func (store *GormRateLimitDataStore) Increment(ctx context.Context, key string, n int64, expiredAt time.Time) (int64, error) {
+	var counter model.RateLimitCounter
+	err := store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"hits": gorm.Expr("hits + ?", n)}),
+		}).Create(&model.RateLimitCounter{ID: key, Hits: n, ExpiredAt: expiredAt}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where(&model.RateLimitCounter{ID: key}).First(&counter).Error
+	})
+	return counter.Hits, err
+}
+
+func (store *GormRateLimitDataStore) DeleteExpired(ctx context.Context) (int64, error) {
+	tx := store.db.WithContext(ctx).Where("expired_at < ?", time.Now().UTC()).Delete(&model.RateLimitCounter{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type GormRateLimitDataStoreTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	store   *GormRateLimitDataStore
+	counter *model.RateLimitCounter
+}
+
+func TestNewGormRateLimitDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	store, err := NewGormRateLimitDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	require.NoError(t, db.Create(createTestRateLimitCounter(1, time.Now().UTC())).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormRateLimitDataStore(t *testing.T) {
+	suite.Run(t, new(GormRateLimitDataStoreTestSuite))
+}
+
+func (s *GormRateLimitDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.RateLimitCounter{}))
+
+	s.store = &GormRateLimitDataStore{db: gormDB}
+	s.counter = createTestRateLimitCounter(3, time.Now().UTC().Add(time.Minute))
+	require.NoError(s.T(), s.db.Create(s.counter).Error)
+}
+
+func (s *GormRateLimitDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormRateLimitDataStoreTestSuite) TestIncrement() {
+	hits, err := s.store.Increment(context.Background(), s.counter.ID, 2, s.counter.ExpiredAt)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(5), hits)
+
+	// Zero only reads the hits
+	hits, err = s.store.Increment(context.Background(), s.counter.ID, 0, s.counter.ExpiredAt)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(5), hits)
+
+	var queryCounter model.RateLimitCounter
+	require.NoError(s.T(), s.db.Where("id", s.counter.ID).First(&queryCounter).Error)
+	require.Equal(s.T(), int64(5), queryCounter.Hits)
+}
+
+func (s *GormRateLimitDataStoreTestSuite) TestIncrementNewKey() {
+	hits, err := s.store.Increment(context.Background(), "new-key", 1, time.Now().UTC().Add(time.Minute))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), hits)
+
+	hits, err = s.store.Increment(context.Background(), "new-key", 1, time.Now().UTC().Add(time.Minute))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(2), hits)
+}
+
+func (s *GormRateLimitDataStoreTestSuite) TestDeleteExpired() {
+	expiredCounter := createTestRateLimitCounter(1, time.Now().UTC().Add(-time.Minute))
+	require.NoError(s.T(), s.db.Create(expiredCounter).Error)
+
+	deleted, err := s.store.DeleteExpired(context.Background())
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), int64(1), deleted)
+
+	var count int64
+	require.NoError(s.T(), s.db.Model(&model.RateLimitCounter{}).Where("id", expiredCounter.ID).Count(&count).Error)
+	require.Equal(s.T(), int64(0), count)
+	require.NoError(s.T(), s.db.Model(&model.RateLimitCounter{}).Where("id", s.counter.ID).Count(&count).Error)
+	require.Equal(s.T(), int64(1), count)
+}
@@ -59,6 +59,28 @@ func createTestFile(userID uint, expired bool) *model.File {
 	return file
 }
 
+func createTestBundle(userID uint, expired bool, fileCount int) *model.Bundle {
+	bundle := &model.Bundle{
+		ID:        faker.Password(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		ExpiredAt: time.Now().Add(time.Hour),
+		Token:     faker.Password(),
+		UserID:    userID,
+	}
+	if expired {
+		bundle.ExpiredAt = time.Now().Add(-1 * time.Hour)
+	}
+	for i := 0; i < fileCount; i++ {
+		file := createTestFile(userID, expired)
+		file.Token = ""
+		file.BundleID = &bundle.ID
+		file.ExpiredAt = bundle.ExpiredAt
+		bundle.Files = append(bundle.Files, *file)
+	}
+	return bundle
+}
+
 func createTestImage(userID uint) *model.Image {
 	return &model.Image{
 		ID:               uint(rand.Uint32()),
@@ -71,3 +93,71 @@ func createTestImage(userID uint) *model.Image {
 		DeletedAt:        gorm.DeletedAt{},
 	}
 }
+
+func createTestBlob(userID uint, refCount uint) *model.Blob {
+	return &model.Blob{
+		ID:        faker.Password(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    userID,
+		Checksum:  faker.UUIDDigit(),
+		Size:      uint64(rand.Uint32()),
+		RefCount:  refCount,
+	}
+}
+
+func createTestAuditEvent(action string, actorID uint, ownerID uint, createdAt time.Time) *model.AuditEvent {
+	fileID := faker.Password()
+	return &model.AuditEvent{
+		CreatedAt: createdAt,
+		Action:    action,
+		ActorID:   actorID,
+		OwnerID:   ownerID,
+		FileID:    &fileID,
+		RequestID: faker.UUIDDigit(),
+		IP:        faker.IPv4(),
+	}
+}
+
+func createTestDownload(fileID string, createdAt time.Time) *model.Download {
+	return &model.Download{
+		CreatedAt:       createdAt,
+		FileID:          fileID,
+		Completed:       rand.Intn(2) == 0,
+		Bytes:           uint64(rand.Uint32()),
+		Referrer:        faker.DomainName(),
+		UserAgentFamily: "Firefox",
+	}
+}
+
+func createTestWebhook(userID uint, events string) *model.Webhook {
+	return &model.Webhook{
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userID,
+		URL:       faker.URL(),
+		Secret:    faker.Password(),
+		Events:    events,
+	}
+}
+
+func createTestWebhookDelivery(webhookID uint, createdAt time.Time) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		CreatedAt:  createdAt,
+		WebhookID:  webhookID,
+		EventID:    faker.UUIDDigit(),
+		Event:      "file.created",
+		Attempt:    1,
+		StatusCode: 200,
+		Success:    true,
+		DurationMs: rand.Int63n(1000),
+	}
+}
+
+func createTestRateLimitCounter(hits int64, expiredAt time.Time) *model.RateLimitCounter {
+	return &model.RateLimitCounter{
+		ID:        faker.UUIDDigit(),
+		Hits:      hits,
+		ExpiredAt: expiredAt,
+	}
+}
@@ -0,0 +1,66 @@
+package data
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+const timeoutKey = "timeout:statement"
+
+// TimeoutPlugin cancels the queries that take longer than the timeout.
+// The timeout covers all callbacks of the query, including the associations and preloads.
+// Row is not limited, because its rows are scanned after the callbacks return.
+type TimeoutPlugin struct {
+	Timeout time.Duration
+}
+
+func (p *TimeoutPlugin) Name() string {
+	return "timeout"
+}
+
+func (p *TimeoutPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	registers := []func() error{
+		func() error { return callback.Create().Before("*").Register("timeout:before_create", p.before) },
+		func() error { return callback.Create().After("*").Register("timeout:after_create", p.after) },
+		func() error { return callback.Query().Before("*").Register("timeout:before_query", p.before) },
+		func() error { return callback.Query().After("*").Register("timeout:after_query", p.after) },
+		func() error { return callback.Update().Before("*").Register("timeout:before_update", p.before) },
+		func() error { return callback.Update().After("*").Register("timeout:after_update", p.after) },
+		func() error { return callback.Delete().Before("*").Register("timeout:before_delete", p.before) },
+		func() error { return callback.Delete().After("*").Register("timeout:after_delete", p.after) },
+		func() error { return callback.Raw().Before("*").Register("timeout:before_raw", p.before) },
+		func() error { return callback.Raw().After("*").Register("timeout:after_raw", p.after) },
+	}
+	for _, register := range registers {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statementTimeout is kept on the statement to restore its context after the query
+type statementTimeout struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+func (p *TimeoutPlugin) before(db *gorm.DB) {
+	parent := db.Statement.Context
+	ctx, cancel := context.WithTimeout(parent, p.Timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(timeoutKey, statementTimeout{parent: parent, cancel: cancel})
+}
+
+func (p *TimeoutPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(timeoutKey)
+	if !ok {
+		return
+	}
+	if timeout, ok := value.(statementTimeout); ok {
+		timeout.cancel()
+		db.Statement.Context = timeout.parent
+	}
+}
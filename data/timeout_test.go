@@ -0,0 +1,46 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPlugin(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, destroyTestGormDB())
+	}()
+	require.NoError(t, db.Use(&TimeoutPlugin{Timeout: time.Minute}))
+	store, err := NewGormBundleDataStore(db)
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.File{}))
+
+	// The files of the bundle are created and preloaded within the timeout of the query
+	bundle := createTestBundle(0, false, 2)
+	require.NoError(t, store.Create(context.Background(), bundle))
+	foundBundle, err := store.FindByToken(context.Background(), bundle.Token)
+	require.NoError(t, err)
+	require.Len(t, foundBundle.Files, 2)
+
+	// The statement is reused with the context of the caller after the query
+	tx := db.WithContext(context.Background()).Model(&model.File{}).Where("bundle_id", bundle.ID)
+	var count int64
+	require.NoError(t, tx.Count(&count).Error)
+	require.NoError(t, tx.Count(&count).Error)
+	require.Equal(t, int64(2), count)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.FindByToken(ctx, bundle.Token)
+	require.ErrorIs(t, err, context.Canceled)
+
+	shortDB, err := createTestGormDB()
+	require.NoError(t, err)
+	require.NoError(t, shortDB.Use(&TimeoutPlugin{Timeout: time.Nanosecond}))
+	_, err = (&GormBundleDataStore{db: shortDB}).FindByToken(context.Background(), bundle.Token)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
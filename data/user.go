@@ -1,17 +1,19 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"github.com/thetkpark/cscms-temp-storage/data/model"
 	"gorm.io/gorm"
 )
 
 type UserDataStore interface {
-	FindByProviderAndEmail(provider string, email string) (*model.User, error)
-	FindById(userId uint) (*model.User, error)
-	Create(email string, username string, provider string, avatarUrl string) (*model.User, error)
-	FindByAPIKey(key string) (*model.User, error)
-	UpdateAPIKey(userID uint, newKey string) error
+	FindByProviderAndEmail(ctx context.Context, provider string, email string) (*model.User, error)
+	FindById(ctx context.Context, userId uint) (*model.User, error)
+	Create(ctx context.Context, email string, username string, provider string, avatarUrl string) (*model.User, error)
+	FindByAPIKey(ctx context.Context, key string) (*model.User, error)
+	UpdateAPIKey(ctx context.Context, userID uint, newKey string) error
+	UpdateRole(ctx context.Context, userID uint, role string) error
 }
 
 type GormUserDataStore struct {
@@ -27,9 +29,9 @@ func NewGormUserDataStore(db *gorm.DB) (*GormUserDataStore, error) {
 	}, nil
 }
 
-func (d *GormUserDataStore) FindById(userId uint) (*model.User, error) {
+func (d *GormUserDataStore) FindById(ctx context.Context, userId uint) (*model.User, error) {
 	var user model.User
-	tx := d.db.Where(&model.User{ID: userId}).First(&user)
+	tx := d.db.WithContext(ctx).Where(&model.User{ID: userId}).First(&user)
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -40,9 +42,9 @@ func (d *GormUserDataStore) FindById(userId uint) (*model.User, error) {
 	return &user, nil
 }
 
-func (d *GormUserDataStore) FindByProviderAndEmail(provider string, email string) (*model.User, error) {
+func (d *GormUserDataStore) FindByProviderAndEmail(ctx context.Context, provider string, email string) (*model.User, error) {
 	var user model.User
-	tx := d.db.Where(&model.User{Email: email, Provider: provider}).First(&user)
+	tx := d.db.WithContext(ctx).Where(&model.User{Email: email, Provider: provider}).First(&user)
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -52,14 +54,14 @@ func (d *GormUserDataStore) FindByProviderAndEmail(provider string, email string
 	return &user, nil
 }
 
-func (d *GormUserDataStore) Create(email string, username string, provider string, avatarUrl string) (*model.User, error) {
+func (d *GormUserDataStore) Create(ctx context.Context, email string, username string, provider string, avatarUrl string) (*model.User, error) {
 	user := &model.User{
 		Email:     email,
 		Username:  username,
 		Provider:  provider,
 		AvatarURL: avatarUrl,
 	}
-	tx := d.db.Create(user)
+	tx := d.db.WithContext(ctx).Create(user)
 
 	if tx.Error != nil {
 		return nil, tx.Error
@@ -67,9 +69,9 @@ func (d *GormUserDataStore) Create(email string, username string, provider strin
 	return user, nil
 }
 
-func (d *GormUserDataStore) FindByAPIKey(key string) (*model.User, error) {
+func (d *GormUserDataStore) FindByAPIKey(ctx context.Context, key string) (*model.User, error) {
 	var user model.User
-	tx := d.db.Where(&model.User{APIKey: key}).First(&user)
+	tx := d.db.WithContext(ctx).Where(&model.User{APIKey: key}).First(&user)
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -79,7 +81,12 @@ func (d *GormUserDataStore) FindByAPIKey(key string) (*model.User, error) {
 	return &user, nil
 }
 
-func (d *GormUserDataStore) UpdateAPIKey(userID uint, newKey string) error {
-	tx := d.db.Model(&model.User{}).Where(&model.User{ID: userID}).Update("api_key", newKey)
+func (d *GormUserDataStore) UpdateAPIKey(ctx context.Context, userID uint, newKey string) error {
+	tx := d.db.WithContext(ctx).Model(&model.User{}).Where(&model.User{ID: userID}).Update("api_key", newKey)
+	return tx.Error
+}
+
+func (d *GormUserDataStore) UpdateRole(ctx context.Context, userID uint, role string) error {
+	tx := d.db.WithContext(ctx).Model(&model.User{}).Where(&model.User{ID: userID}).Update("role", role)
 	return tx.Error
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"github.com/bxcodec/faker/v3"
 	"github.com/go-test/deep"
 	"github.com/stretchr/testify/require"
@@ -54,7 +55,7 @@ func (s *GormUserDataStoreTestSuite) AfterTest(_, _ string) {
 
 func (s *GormUserDataStoreTestSuite) TestCreate() {
 	newUser := createTestUser("google")
-	user, err := s.store.Create(newUser.Email, newUser.Username, newUser.Provider, newUser.AvatarURL)
+	user, err := s.store.Create(context.Background(), newUser.Email, newUser.Username, newUser.Provider, newUser.AvatarURL)
 	require.NoError(s.T(), err)
 
 	var queryUser model.User
@@ -63,47 +64,47 @@ func (s *GormUserDataStoreTestSuite) TestCreate() {
 }
 
 func (s *GormUserDataStoreTestSuite) TestFoundByID() {
-	foundUser, err := s.store.FindById(s.user.ID)
+	foundUser, err := s.store.FindById(context.Background(), s.user.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(foundUser, s.user))
 }
 
 func (s *GormUserDataStoreTestSuite) TestNotFoundByID() {
 	newUser := createTestUser("github")
-	foundUser, err := s.store.FindById(newUser.ID)
+	foundUser, err := s.store.FindById(context.Background(), newUser.ID)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), foundUser)
 }
 
 func (s *GormUserDataStoreTestSuite) TestFoundByProviderAndEmail() {
-	foundUser, err := s.store.FindByProviderAndEmail(s.user.Provider, s.user.Email)
+	foundUser, err := s.store.FindByProviderAndEmail(context.Background(), s.user.Provider, s.user.Email)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(foundUser, s.user))
 }
 
 func (s *GormUserDataStoreTestSuite) TestNotFoundByProviderAndEmail() {
 	newUser := createTestUser("github")
-	foundUser, err := s.store.FindByProviderAndEmail(newUser.Provider, newUser.Email)
+	foundUser, err := s.store.FindByProviderAndEmail(context.Background(), newUser.Provider, newUser.Email)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), foundUser)
 }
 
 func (s *GormUserDataStoreTestSuite) TestFoundByAPIKey() {
-	foundUser, err := s.store.FindByAPIKey(s.user.APIKey)
+	foundUser, err := s.store.FindByAPIKey(context.Background(), s.user.APIKey)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), deep.Equal(foundUser, s.user))
 }
 
 func (s *GormUserDataStoreTestSuite) TestNotFoundByAPIKey() {
 	newUser := createTestUser("github")
-	foundUser, err := s.store.FindByAPIKey(newUser.APIKey)
+	foundUser, err := s.store.FindByAPIKey(context.Background(), newUser.APIKey)
 	require.NoError(s.T(), err)
 	require.Nil(s.T(), foundUser)
 }
 
 func (s *GormUserDataStoreTestSuite) TestUpdateAPIKey() {
 	newApiKey := faker.UUIDDigit()
-	require.NoError(s.T(), s.store.UpdateAPIKey(s.user.ID, newApiKey))
+	require.NoError(s.T(), s.store.UpdateAPIKey(context.Background(), s.user.ID, newApiKey))
 
 	queryUser := &model.User{}
 	err := s.db.Where(&model.User{APIKey: newApiKey}).First(queryUser).Error
@@ -111,10 +112,18 @@ func (s *GormUserDataStoreTestSuite) TestUpdateAPIKey() {
 	require.Equal(s.T(), queryUser.ID, s.user.ID)
 }
 
+func (s *GormUserDataStoreTestSuite) TestUpdateRole() {
+	require.NoError(s.T(), s.store.UpdateRole(context.Background(), s.user.ID, model.RoleAdmin))
+
+	queryUser := &model.User{}
+	require.NoError(s.T(), s.db.Where(&model.User{ID: s.user.ID}).First(queryUser).Error)
+	require.Equal(s.T(), model.RoleAdmin, queryUser.Role)
+}
+
 func (s *GormUserDataStoreTestSuite) TestUpdateAPIKeyOnNotFoundUser() {
 	newUser := createTestUser("github")
 	newApiKey := faker.UUIDDigit()
-	require.NoError(s.T(), s.store.UpdateAPIKey(newUser.ID, newApiKey))
+	require.NoError(s.T(), s.store.UpdateAPIKey(context.Background(), newUser.ID, newApiKey))
 
 	queryUser := &model.User{}
 	err := s.db.Where(&model.User{APIKey: newApiKey}).First(queryUser).Error
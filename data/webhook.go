@@ -0,0 +1,88 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+)
+
+type WebhookDataStore interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	FindByID(ctx context.Context, webhookID uint) (*model.Webhook, error)
+	FindByUserID(ctx context.Context, userID uint) ([]model.Webhook, error)
+	FindByUserIDAndEvent(ctx context.Context, userID uint, eventType string) ([]model.Webhook, error)
+	DeleteByID(ctx context.Context, webhookID uint) error
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, webhookID uint, limit int) ([]model.WebhookDelivery, error)
+}
+
+type GormWebhookDataStore struct {
+	db *gorm.DB
+}
+
+func NewGormWebhookDataStore(db *gorm.DB) (*GormWebhookDataStore, error) {
+	if err := db.AutoMigrate(&model.Webhook{}, &model.WebhookDelivery{}); err != nil {
+		return nil, err
+	}
+	return &GormWebhookDataStore{
+		db: db,
+	}, nil
+}
+
+func (store *GormWebhookDataStore) Create(ctx context.Context, webhook *model.Webhook) error {
+	tx := store.db.WithContext(ctx).Create(webhook)
+	return tx.Error
+}
+
+func (store *GormWebhookDataStore) FindByID(ctx context.Context, webhookID uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	tx := store.db.WithContext(ctx).First(&webhook, webhookID)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &webhook, tx.Error
+}
+
+func (store *GormWebhookDataStore) FindByUserID(ctx context.Context, userID uint) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	tx := store.db.WithContext(ctx).Where("user_id", userID).Order("id").Find(&webhooks)
+	return webhooks, tx.Error
+}
+
+// FindByUserIDAndEvent returns the webhooks of the user that are subscribed to the event type
+func (store *GormWebhookDataStore) FindByUserIDAndEvent(ctx context.Context, userID uint, eventType string) ([]model.Webhook, error) {
+	webhooks, err := store.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed := make([]model.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+// DeleteByID deletes the webhook with its delivery log
+func (store *GormWebhookDataStore) DeleteByID(ctx context.Context, webhookID uint) error {
+	return store.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id", webhookID).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Webhook{}, webhookID).Error
+	})
+}
+
+func (store *GormWebhookDataStore) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	tx := store.db.WithContext(ctx).Create(delivery)
+	return tx.Error
+}
+
+// FindDeliveries returns the latest deliveries of the webhook from the newest
+func (store *GormWebhookDataStore) FindDeliveries(ctx context.Context, webhookID uint, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	tx := store.db.WithContext(ctx).Where("webhook_id", webhookID).Order("created_at DESC").Order("id DESC").Limit(limit).Find(&deliveries)
+	return deliveries, tx.Error
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type GormWebhookDataStoreTestSuite struct {
+	suite.Suite
+	db       *gorm.DB
+	store    *GormWebhookDataStore
+	webhooks []*model.Webhook
+}
+
+func TestNewGormWebhookDataStore(t *testing.T) {
+	db, err := createTestGormDB()
+	require.NoError(t, err)
+	store, err := NewGormWebhookDataStore(db)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	webhook := createTestWebhook(1, "file.created")
+	require.NoError(t, db.Create(webhook).Error)
+	require.NoError(t, db.Create(createTestWebhookDelivery(webhook.ID, time.Now())).Error)
+	require.NoError(t, destroyTestGormDB())
+}
+
+func TestGormWebhookDataStore(t *testing.T) {
+	suite.Run(t, new(GormWebhookDataStoreTestSuite))
+}
+
+func (s *GormWebhookDataStoreTestSuite) SetupTest() {
+	gormDB, err := createTestGormDB()
+	require.NoError(s.T(), err)
+	s.db = gormDB
+
+	require.NoError(s.T(), gormDB.AutoMigrate(&model.Webhook{}, &model.WebhookDelivery{}))
+
+	s.store = &GormWebhookDataStore{db: gormDB}
+	s.webhooks = []*model.Webhook{
+		createTestWebhook(1, "file.created,file.deleted"),
+		createTestWebhook(1, "image.created"),
+		createTestWebhook(2, "file.created"),
+	}
+	for _, webhook := range s.webhooks {
+		require.NoError(s.T(), s.db.Create(webhook).Error)
+	}
+}
+
+func (s *GormWebhookDataStoreTestSuite) AfterTest(_, _ string) {
+	require.NoError(s.T(), destroyTestGormDB())
+}
+
+func (s *GormWebhookDataStoreTestSuite) TestCreate() {
+	webhook := createTestWebhook(3, "file.expired")
+	require.NoError(s.T(), s.store.Create(context.Background(), webhook))
+	require.NotZero(s.T(), webhook.ID)
+}
+
+func (s *GormWebhookDataStoreTestSuite) TestFindByID() {
+	webhook, err := s.store.FindByID(context.Background(), s.webhooks[0].ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), s.webhooks[0].URL, webhook.URL)
+	require.Equal(s.T(), s.webhooks[0].Secret, webhook.Secret)
+
+	webhook, err = s.store.FindByID(context.Background(), 100)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), webhook)
+}
+
+func (s *GormWebhookDataStoreTestSuite) TestFindByUserID() {
+	webhooks, err := s.store.FindByUserID(context.Background(), 1)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), webhooks, 2)
+}
+
+func (s *GormWebhookDataStoreTestSuite) TestFindByUserIDAndEvent() {
+	webhooks, err := s.store.FindByUserIDAndEvent(context.Background(), 1, "file.deleted")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), webhooks, 1)
+	require.Equal(s.T(), s.webhooks[0].ID, webhooks[0].ID)
+
+	// The event type must match exactly
+	webhooks, err = s.store.FindByUserIDAndEvent(context.Background(), 1, "file")
+	require.NoError(s.T(), err)
+	require.Len(s.T(), webhooks, 0)
+}
+
+func (s *GormWebhookDataStoreTestSuite) TestDeliveries() {
+	now := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		delivery := createTestWebhookDelivery(s.webhooks[0].ID, now.Add(time.Duration(i)*time.Minute))
+		require.NoError(s.T(), s.store.CreateDelivery(context.Background(), delivery))
+	}
+	require.NoError(s.T(), s.store.CreateDelivery(context.Background(), createTestWebhookDelivery(s.webhooks[1].ID, now)))
+
+	deliveries, err := s.store.FindDeliveries(context.Background(), s.webhooks[0].ID, 2)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, 2)
+	require.True(s.T(), deliveries[0].CreatedAt.After(deliveries[1].CreatedAt))
+
+	// The deliveries are deleted with the webhook
+	require.NoError(s.T(), s.store.DeleteByID(context.Background(), s.webhooks[0].ID))
+	webhook, err := s.store.FindByID(context.Background(), s.webhooks[0].ID)
+	require.NoError(s.T(), err)
+	require.Nil(s.T(), webhook)
+	deliveries, err = s.store.FindDeliveries(context.Background(), s.webhooks[0].ID, 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, 0)
+	deliveries, err = s.store.FindDeliveries(context.Background(), s.webhooks[1].ID, 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, 1)
+}
@@ -5,20 +5,33 @@ go 1.15
 require (
 	cloud.google.com/go v0.90.0 // indirect
 	github.com/Azure/azure-sdk-for-go/sdk/storage/azblob v0.2.0
+	github.com/BurntSushi/toml v0.4.1
+	github.com/alecthomas/chroma v0.10.0
+	github.com/alicebob/miniredis/v2 v2.30.0
 	github.com/arsmn/fiber-swagger/v2 v2.20.0
 	github.com/bxcodec/faker/v3 v3.7.0
 	github.com/caarlos0/env/v6 v6.8.0
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/go-test/deep v1.0.8
 	github.com/gofiber/fiber/v2 v2.32.0
 	github.com/golang-jwt/jwt v3.2.1+incompatible
 	github.com/markbates/goth v1.68.0
 	github.com/matoous/go-nanoid/v2 v2.0.0
 	github.com/minio/sio v0.3.0
+	github.com/prometheus/client_golang v1.11.0
 	github.com/shareed2k/goth_fiber v0.2.3
 	github.com/stretchr/testify v1.7.0
 	github.com/swaggo/swag v1.7.6
+	github.com/valyala/fasthttp v1.35.0
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	go.uber.org/zap v1.19.1
 	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
+	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65
+	gopkg.in/yaml.v2 v2.4.0
 	gorm.io/driver/mysql v1.1.2
 	gorm.io/driver/sqlite v1.2.6
 	gorm.io/gorm v1.22.3
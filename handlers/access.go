@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"go.uber.org/zap"
+	"time"
+)
+
+// requestID is the ID set by the request ID middleware
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestid.ConfigDefault.ContextKey).(string)
+	return id
+}
+
+// NewAccessLogger logs every request with the request ID after it is handled.
+// It must be used after the request ID middleware.
+func NewAccessLogger(log *zap.SugaredLogger) fiber.Handler {
+	accessLog := log.Named("access")
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		// The error handler sets the status after the middleware returns
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		// Reading the body of the stream would consume it
+		bytes := c.Response().Header.ContentLength()
+		if !c.Response().IsBodyStream() {
+			bytes = len(c.Response().Body())
+		}
+
+		var userID uint
+		if user, ok := c.UserContext().Value("user").(*model.User); ok {
+			userID = user.ID
+		}
+
+		accessLog.Infow("request",
+			"request_id", requestID(c),
+			"method", c.Method(),
+			"route", c.Route().Path,
+			"path", c.Path(),
+			"status", status,
+			"bytes", bytes,
+			"duration", time.Since(start),
+			"user_id", userID,
+			"token", c.Params("token"),
+			"ip", c.IP(),
+		)
+		return err
+	}
+}
+
+// newDownloadEvent creates the download event of the file from the request.
+// The values are copied, because the event can be sent after the request is reused.
+func newDownloadEvent(c *fiber.Ctx, fileInfo *model.File) *event.DownloadEvent {
+	var userID uint
+	if user, ok := c.UserContext().Value("user").(*model.User); ok {
+		userID = user.ID
+	}
+	return &event.DownloadEvent{
+		Time:      time.Now().UTC(),
+		RequestID: utils.CopyString(requestID(c)),
+		FileID:    fileInfo.ID,
+		Token:     fileInfo.Token,
+		Filename:  fileInfo.Filename,
+		Bytes:     fileInfo.FileSize,
+		UserID:    userID,
+		IP:        c.IP(),
+		UserAgent: utils.CopyString(c.Get(fiber.HeaderUserAgent)),
+	}
+}
+
+// sendDownloadEvent sends the event to the sink, the download is not failed if it cannot be sent
+func (h *FileRoutesHandler) sendDownloadEvent(downloadEvent *event.DownloadEvent) {
+	if err := h.downloadEvents.SendDownload(downloadEvent); err != nil {
+		h.log.Errorw("unable to send download event", "error", err, "fileID", downloadEvent.FileID)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Use(requestid.New())
+	app.Use(NewAccessLogger(zap.New(core).Sugar()))
+	app.Get("/:token", func(c *fiber.Ctx) error {
+		if c.Params("token") == "missing" {
+			return fiber.NewError(fiber.StatusNotFound, "File not found")
+		}
+		return c.SendString("hello")
+	})
+
+	res, err := app.Test(httptest.NewRequest("GET", "/abc", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	requestID := res.Header.Get(fiber.HeaderXRequestID)
+	require.NotEmpty(t, requestID)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	require.Equal(t, requestID, fields["request_id"])
+	require.Equal(t, "/:token", fields["route"])
+	require.Equal(t, "abc", fields["token"])
+	require.Equal(t, int64(fiber.StatusOK), fields["status"])
+	require.Equal(t, int64(5), fields["bytes"])
+
+	// The request ID is returned in the error response
+	res, err = app.Test(httptest.NewRequest("GET", "/missing", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusNotFound, res.StatusCode)
+	var errorResponse ErrorResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&errorResponse))
+	require.Equal(t, res.Header.Get(fiber.HeaderXRequestID), errorResponse.RequestID)
+	require.Equal(t, int64(fiber.StatusNotFound), logs.All()[1].ContextMap()["status"])
+}
+
+func TestNewHTTPErrorLogsRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	log := zap.New(core).Sugar()
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Use(requestid.New())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return NewHTTPError(c, log, fiber.StatusInternalServerError, "unable to get file", errors.New("broken"))
+	})
+
+	res, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusInternalServerError, res.StatusCode)
+
+	// The server error is logged with the request ID returned to the client
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	require.Equal(t, res.Header.Get(fiber.HeaderXRequestID), fields["requestID"])
+	require.Equal(t, "broken", fields["error"])
+}
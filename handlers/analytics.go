@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAnalyticsPeriod = 30 * 24 * time.Hour
+	maxAnalyticsBuckets    = 1000
+)
+
+var analyticsIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// FileAnalytics is the download statistics of the file in the time range
+type FileAnalytics struct {
+	FileID   string           `json:"file_id"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Interval string           `json:"interval"`
+	Totals   DownloadTotals   `json:"totals"`
+	Series   []DownloadBucket `json:"series"`
+}
+
+type DownloadTotals struct {
+	Completed uint   `json:"completed"`
+	Aborted   uint   `json:"aborted"`
+	Bytes     uint64 `json:"bytes"`
+	// Referrers is the number of downloads from each referrer host, empty for direct downloads
+	Referrers map[string]uint `json:"referrers"`
+	// UserAgents is the number of downloads from each user agent family
+	UserAgents map[string]uint `json:"user_agents"`
+}
+
+// DownloadBucket is the downloads in the interval starting from the time
+type DownloadBucket struct {
+	Time      time.Time `json:"time"`
+	Completed uint      `json:"completed"`
+	Aborted   uint      `json:"aborted"`
+	Bytes     uint64    `json:"bytes"`
+}
+
+// GetFileAnalytics handlers
+// @Summary Get download analytics of the file
+// @Description Get the totals and time series of the downloads of the own file. A download is completed only when the whole file is sent
+// @Tags File
+// @Produce  json
+// @Param        fileID       path      string      true  "File ID"
+// @Param        from       query      string      false  "Start time in RFC3339, 30 days before to by default"
+// @Param        to       query      string      false  "End time in RFC3339 (exclusive), now by default"
+// @Param        interval       query      string      false  "Interval of the time series (hour, day)"
+// @Success      200  {object}  handlers.FileAnalytics
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/file/{fileID}/analytics [get]
+func (h *FileRoutesHandler) GetFileAnalytics(c *fiber.Ctx) error {
+	fileModel, ok := c.UserContext().Value("file").(*model.File)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
+	}
+
+	intervalName := c.Query("interval", "day")
+	interval, ok := analyticsIntervals[intervalName]
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "interval must be hour or day", nil)
+	}
+	to := time.Now().UTC()
+	if toString := c.Query("to"); len(toString) > 0 {
+		parsed, err := time.Parse(time.RFC3339, toString)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "to must be in RFC3339 format", nil)
+		}
+		to = parsed.UTC()
+	}
+	from := to.Add(-defaultAnalyticsPeriod)
+	if fromString := c.Query("from"); len(fromString) > 0 {
+		parsed, err := time.Parse(time.RFC3339, fromString)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "from must be in RFC3339 format", nil)
+		}
+		from = parsed.UTC()
+	}
+	if !from.Before(to) {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "from must be before to", nil)
+	}
+	if to.Sub(from)/interval >= maxAnalyticsBuckets {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("Time range must be less than %d intervals", maxAnalyticsBuckets), nil)
+	}
+
+	downloads, err := h.downloadDataStore.FindByFileID(c.UserContext(), fileModel.ID, from, to)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to query downloads", err)
+	}
+
+	analytics := aggregateDownloads(downloads, from, to, interval)
+	analytics.FileID = fileModel.ID
+	analytics.Interval = intervalName
+	return c.JSON(analytics)
+}
+
+// aggregateDownloads counts the downloads in the time range.
+// The series has a bucket for every interval, including the intervals without download.
+func aggregateDownloads(downloads []model.Download, from time.Time, to time.Time, interval time.Duration) FileAnalytics {
+	analytics := FileAnalytics{
+		From: from,
+		To:   to,
+		Totals: DownloadTotals{
+			Referrers:  make(map[string]uint),
+			UserAgents: make(map[string]uint),
+		},
+		Series: []DownloadBucket{},
+	}
+	start := from.Truncate(interval)
+	for bucketTime := start; bucketTime.Before(to); bucketTime = bucketTime.Add(interval) {
+		analytics.Series = append(analytics.Series, DownloadBucket{Time: bucketTime})
+	}
+
+	for _, download := range downloads {
+		if download.CreatedAt.Before(from) || !download.CreatedAt.Before(to) {
+			continue
+		}
+		bucket := &analytics.Series[int(download.CreatedAt.Sub(start)/interval)]
+		if download.Completed {
+			analytics.Totals.Completed++
+			bucket.Completed++
+		} else {
+			analytics.Totals.Aborted++
+			bucket.Aborted++
+		}
+		analytics.Totals.Bytes += download.Bytes
+		bucket.Bytes += download.Bytes
+		analytics.Totals.Referrers[download.Referrer]++
+		analytics.Totals.UserAgents[download.UserAgentFamily]++
+	}
+	return analytics
+}
+
+// downloadSource is the client information of the download, copied from the request
+type downloadSource struct {
+	referrer        string
+	userAgentFamily string
+}
+
+func newDownloadSource(c *fiber.Ctx) downloadSource {
+	return downloadSource{
+		referrer:        referrerHost(c.Get(fiber.HeaderReferer)),
+		userAgentFamily: userAgentFamily(c.Get(fiber.HeaderUserAgent)),
+	}
+}
+
+// recordDownload saves the download record.
+// The visited count is only increased and the webhooks are only notified by the completed download.
+func (h *FileRoutesHandler) recordDownload(ctx context.Context, fileInfo *model.File, source downloadSource, bytes uint64) {
+	download := &model.Download{
+		CreatedAt:       time.Now().UTC(),
+		FileID:          fileInfo.ID,
+		Completed:       bytes >= fileInfo.FileSize,
+		Bytes:           bytes,
+		Referrer:        source.referrer,
+		UserAgentFamily: source.userAgentFamily,
+	}
+	if err := h.downloadDataStore.Create(ctx, download); err != nil {
+		h.log.Errorw("unable to save download", "error", err, "fileID", fileInfo.ID)
+	}
+	if !download.Completed {
+		return
+	}
+	if err := h.fileDataStore.IncreaseVisited(ctx, fileInfo.ID); err != nil {
+		h.log.Errorw("unable to increase count", "error", err, "fileID", fileInfo.ID)
+	}
+	h.webhookDispatcher.Dispatch(fileInfo.UserID, webhook.NewFileEvent(webhook.EventFileDownloaded, fileInfo))
+}
+
+// downloadReader counts the bytes sent to the client and records the download when the stream is closed.
+// The server closes the stream after the whole content is sent or the connection is broken.
+type downloadReader struct {
+	reader   io.ReadCloser
+	bytes    uint64
+	once     sync.Once
+	onClosed func(bytes uint64)
+}
+
+func (r *downloadReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.bytes += uint64(n)
+	return n, err
+}
+
+func (r *downloadReader) Close() error {
+	r.once.Do(func() {
+		r.onClosed(r.bytes)
+	})
+	return r.reader.Close()
+}
+
+// newDownloadReader records the download of the file when the reader is closed
+func (h *FileRoutesHandler) newDownloadReader(ctx context.Context, fileInfo *model.File, source downloadSource, reader io.ReadCloser) io.ReadCloser {
+	return &downloadReader{reader: reader, onClosed: func(bytes uint64) {
+		h.recordDownload(ctx, fileInfo, source, bytes)
+	}}
+}
+
+// referrerHost only keeps the host of the referrer, the path can contain private tokens
+func referrerHost(referrer string) string {
+	if len(referrer) == 0 {
+		return ""
+	}
+	referrerURL, err := url.Parse(referrer)
+	if err != nil || len(referrerURL.Hostname()) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(referrerURL.Hostname())
+}
+
+// userAgentFamily returns the coarse family of the user agent.
+// The order matters, because most browsers also contain the names of the others.
+func userAgentFamily(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case len(ua) == 0:
+		return "Unknown"
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "crawler") || strings.Contains(ua, "spider"):
+		return "Bot"
+	case strings.HasPrefix(ua, "curl/"):
+		return "curl"
+	case strings.HasPrefix(ua, "wget/"):
+		return "Wget"
+	case strings.Contains(ua, "edg/") || strings.Contains(ua, "edge/"):
+		return "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		return "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		return "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/") || strings.Contains(ua, "chromium/"):
+		return "Chrome"
+	case strings.Contains(ua, "safari/"):
+		return "Safari"
+	default:
+		return "Other"
+	}
+}
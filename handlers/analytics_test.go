@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregateDownloads(t *testing.T) {
+	from := time.Date(2022, 1, 1, 12, 30, 0, 0, time.UTC)
+	to := time.Date(2022, 1, 3, 12, 0, 0, 0, time.UTC)
+	downloads := []model.Download{
+		{CreatedAt: from, Completed: true, Bytes: 10, Referrer: "example.com", UserAgentFamily: "Firefox"},
+		{CreatedAt: from.Add(time.Hour), Completed: false, Bytes: 3, UserAgentFamily: "curl"},
+		{CreatedAt: time.Date(2022, 1, 3, 11, 0, 0, 0, time.UTC), Completed: true, Bytes: 10, UserAgentFamily: "Firefox"},
+		// Outside of the time range
+		{CreatedAt: to, Completed: true, Bytes: 10},
+	}
+
+	analytics := aggregateDownloads(downloads, from, to, 24*time.Hour)
+	require.Equal(t, uint(2), analytics.Totals.Completed)
+	require.Equal(t, uint(1), analytics.Totals.Aborted)
+	require.Equal(t, uint64(23), analytics.Totals.Bytes)
+	require.Equal(t, map[string]uint{"example.com": 1, "": 2}, analytics.Totals.Referrers)
+	require.Equal(t, map[string]uint{"Firefox": 2, "curl": 1}, analytics.Totals.UserAgents)
+
+	// The buckets start at the beginning of the day and the days without download are included
+	require.Equal(t, []DownloadBucket{
+		{Time: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Completed: 1, Aborted: 1, Bytes: 13},
+		{Time: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{Time: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), Completed: 1, Bytes: 10},
+	}, analytics.Series)
+}
+
+func TestUserAgentFamily(t *testing.T) {
+	cases := []struct {
+		userAgent string
+		family    string
+	}{
+		{"", "Unknown"},
+		{"curl/7.79.1", "curl"},
+		{"Wget/1.21.2", "Wget"},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "Bot"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:99.0) Gecko/20100101 Firefox/99.0", "Firefox"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.75 Safari/537.36", "Chrome"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/100.0.4896.75 Safari/537.36 Edg/100.0.1185.39", "Edge"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 12_3_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15", "Safari"},
+		{"python-requests/2.27.1", "Other"},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.family, userAgentFamily(tc.userAgent), tc.userAgent)
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	require.Equal(t, "", referrerHost(""))
+	require.Equal(t, "example.com", referrerHost("https://Example.com/private/token?key=secret"))
+	require.Equal(t, "unknown", referrerHost("not a url"))
+}
+
+func TestDownloadReader(t *testing.T) {
+	var closedBytes []uint64
+	reader := &downloadReader{
+		reader:   io.NopCloser(strings.NewReader("hello world")),
+		onClosed: func(bytes uint64) { closedBytes = append(closedBytes, bytes) },
+	}
+	_, err := io.CopyN(io.Discard, reader, 5)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.NoError(t, reader.Close())
+
+	// The download is only recorded once
+	require.Equal(t, []uint64{5}, closedBytes)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"github.com/thetkpark/cscms-temp-storage/service/metrics"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type ArchiveRequest struct {
+	IDs []string `json:"ids" form:"ids"`
+}
+
+// DownloadArchive handlers
+// @Summary Download files as zip
+// @Description Download the selected own files as a zip archive
+// @Tags File
+// @Accept  json
+// @Produce  application/zip
+// @Param        request       body      handlers.ArchiveRequest      true  "File IDs"
+// @Success      200
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/file/archive [post]
+func (h *FileRoutesHandler) DownloadArchive(c *fiber.Ctx) error {
+	// Get userId
+	user := c.UserContext().Value("user")
+	userModel, ok := user.(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	var request ArchiveRequest
+	if err := c.BodyParser(&request); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Invalid request body", nil)
+	}
+	if len(request.IDs) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "File IDs must be provided", nil)
+	}
+	if len(request.IDs) > h.limits.MaxArchiveFiles {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("At most %d files can be downloaded at once", h.limits.MaxArchiveFiles), nil)
+	}
+
+	files, err := h.fileDataStore.FindByIDs(c.UserContext(), request.IDs)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to find files by id", err)
+	}
+	filesByID := make(map[string]model.File, len(files))
+	for _, file := range files {
+		filesByID[file.ID] = file
+	}
+
+	// Keep the requested order and make sure every file is still available and owned by the user
+	archiveFiles := make([]model.File, 0, len(request.IDs))
+	added := make(map[string]bool, len(request.IDs))
+	for _, id := range request.IDs {
+		file, ok := filesByID[id]
+		if !ok || file.ExpiredAt.UTC().Before(time.Now().UTC()) {
+			return NewHTTPError(c, h.log, fiber.StatusNotFound, fmt.Sprintf("File %s not found", id), nil)
+		}
+		if file.UserID != userModel.ID {
+			return NewHTTPError(c, h.log, fiber.StatusForbidden, "Forbidden", nil)
+		}
+		if added[id] {
+			continue
+		}
+		added[id] = true
+		archiveFiles = append(archiveFiles, file)
+	}
+
+	return h.sendZipArchive(c, fmt.Sprintf("cscms-storage-%s.zip", time.Now().UTC().Format("20060102-150405")), archiveFiles)
+}
+
+// sendZipArchive streams the files as a zip archive built on the fly.
+// The archive is written directly to the response without buffering on disk.
+func (h *FileRoutesHandler) sendZipArchive(c *fiber.Ctx, archiveName string, files []model.File) error {
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, archiveName))
+	c.Set("Content-Type", "application/zip")
+
+	// The fiber context is reused after the handler returns, so the context and events are taken before streaming
+	ctx := c.UserContext()
+	downloadEvents := make([]*event.DownloadEvent, len(files))
+	auditEvents := make([]*model.AuditEvent, len(files))
+	source := newDownloadSource(c)
+	user, _ := c.UserContext().Value("user").(*model.User)
+	reqID := utils.CopyString(requestID(c))
+	for i := range files {
+		downloadEvents[i] = newDownloadEvent(c, &files[i])
+		auditEvents[i] = newFileAuditEvent(c, model.AuditFileDownload, &files[i])
+	}
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// The writes fail when the client disconnects, so the archive stops and its reads are cancelled with the client
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		// The archive is built while it is sent, so its output is throttled instead of the files
+		zipWriter := zip.NewWriter(h.downloadThrottle.Writer(ctx, w, user))
+		usedNames := make(map[string]int)
+		for i := range files {
+			h.sendDownloadEvent(downloadEvents[i])
+			recordAudit(ctx, h.log, h.auditDataStore, auditEvents[i])
+			if err := h.writeZipEntry(ctx, zipWriter, &files[i], source, uniqueArchiveName(usedNames, files[i].Filename)); err != nil {
+				// Headers are already sent, so the archive can only be left incomplete
+				h.log.Errorw("unable to write file to zip archive", "error", err, "fileID", files[i].ID, "requestID", reqID)
+				return
+			}
+		}
+		if err := zipWriter.Close(); err != nil {
+			h.log.Errorw("unable to close zip archive", "error", err, "requestID", reqID)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			h.log.Errorw("unable to flush zip archive", "error", err, "requestID", reqID)
+		}
+	})
+	return nil
+}
+
+func (h *FileRoutesHandler) writeZipEntry(ctx context.Context, zipWriter *zip.Writer, fileInfo *model.File, source downloadSource, name string) error {
+	entry, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: fileInfo.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	file, err := h.openFile(ctx, fileInfo)
+	if err != nil {
+		return err
+	}
+	body := metrics.NewTransferReader(h.newDownloadReader(ctx, fileInfo, source, file), metrics.Download)
+	defer body.Close()
+
+	_, err = io.Copy(entry, body)
+	return err
+}
+
+// uniqueArchiveName returns the file name that is not used in the archive yet
+func uniqueArchiveName(usedNames map[string]int, filename string) string {
+	name := strings.TrimLeft(filepath.Base(filepath.Clean("/"+filename)), "/")
+	if len(name) == 0 || name == "." {
+		name = "file"
+	}
+
+	count := usedNames[name]
+	usedNames[name] = count + 1
+	if count == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return uniqueArchiveName(usedNames, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), count, ext))
+}
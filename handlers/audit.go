@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+type AuditRouteHandler struct {
+	log            *zap.SugaredLogger
+	auditDataStore data.AuditDataStore
+}
+
+func NewAuditRouteHandler(log *zap.SugaredLogger, auditDataStore data.AuditDataStore) *AuditRouteHandler {
+	return &AuditRouteHandler{
+		log:            log,
+		auditDataStore: auditDataStore,
+	}
+}
+
+// GetOwnAuditEvents handlers
+// @Summary List activity of the user
+// @Description List the audit events done by the user or on the files and images of the user, from the newest.
+// @Description The actor and IP of the events done by other users are not shown
+// @Tags Audit
+// @Produce  json
+// @Param        file_id       query      string      false  "File ID"
+// @Param        action       query      string      false  "Action, e.g. file.download"
+// @Param        from       query      string      false  "Start time in RFC3339"
+// @Param        to       query      string      false  "End time in RFC3339 (exclusive)"
+// @Param        limit       query      int      false  "Maximum number of events (50, at most 500)"
+// @Param        offset       query      int      false  "Number of events to skip"
+// @Success      200  {array}  model.AuditEvent
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/audit [get]
+func (h *AuditRouteHandler) GetOwnAuditEvents(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	filter, err := h.parseAuditFilter(c)
+	if err != nil {
+		return err
+	}
+	filter.UserID = userModel.ID
+	events, err := h.findAuditEvents(c, filter)
+	if err != nil {
+		return err
+	}
+	// The events done by other users on the content of the user only show what is done
+	for i := range events {
+		if events[i].ActorID != userModel.ID {
+			events[i].ActorID = 0
+			events[i].IP = ""
+		}
+	}
+	return c.JSON(events)
+}
+
+// GetAuditEvents handlers
+// @Summary Query audit log
+// @Description Query the audit events of all users, from the newest. Only for admin
+// @Tags Audit
+// @Produce  json
+// @Param        user_id       query      int      false  "Events done by the user or on the content of the user"
+// @Param        file_id       query      string      false  "File ID"
+// @Param        action       query      string      false  "Action, e.g. file.download"
+// @Param        from       query      string      false  "Start time in RFC3339"
+// @Param        to       query      string      false  "End time in RFC3339 (exclusive)"
+// @Param        limit       query      int      false  "Maximum number of events (50, at most 500)"
+// @Param        offset       query      int      false  "Number of events to skip"
+// @Success      200  {array}  model.AuditEvent
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/admin/audit [get]
+func (h *AuditRouteHandler) GetAuditEvents(c *fiber.Ctx) error {
+	filter, err := h.parseAuditFilter(c)
+	if err != nil {
+		return err
+	}
+	if userIDString := c.Query("user_id"); len(userIDString) > 0 {
+		userID, err := strconv.ParseUint(userIDString, 10, 64)
+		if err != nil || userID == 0 {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "user_id must be positive integer", nil)
+		}
+		filter.UserID = uint(userID)
+	}
+	events, err := h.findAuditEvents(c, filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(events)
+}
+
+func (h *AuditRouteHandler) findAuditEvents(c *fiber.Ctx, filter data.AuditFilter) ([]model.AuditEvent, error) {
+	events, err := h.auditDataStore.Find(c.UserContext(), filter)
+	if err != nil {
+		return nil, NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to query audit events", err)
+	}
+	if events == nil {
+		events = []model.AuditEvent{}
+	}
+	return events, nil
+}
+
+// parseAuditFilter parses the filter from the query, except the user
+func (h *AuditRouteHandler) parseAuditFilter(c *fiber.Ctx) (data.AuditFilter, error) {
+	filter := data.AuditFilter{
+		FileID: c.Query("file_id"),
+		Action: c.Query("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	var err error
+	if from := c.Query("from"); len(from) > 0 {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, NewHTTPError(c, h.log, fiber.StatusBadRequest, "from must be in RFC3339 format", nil)
+		}
+	}
+	if to := c.Query("to"); len(to) > 0 {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, NewHTTPError(c, h.log, fiber.StatusBadRequest, "to must be in RFC3339 format", nil)
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, NewHTTPError(c, h.log, fiber.StatusBadRequest, "from must be before to", nil)
+	}
+
+	if limit := c.Query("limit"); len(limit) > 0 {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+			return filter, NewHTTPError(c, h.log, fiber.StatusBadRequest, "limit must be positive integer", nil)
+		}
+		if filter.Limit > maxAuditLimit {
+			filter.Limit = maxAuditLimit
+		}
+	}
+	if offset := c.Query("offset"); len(offset) > 0 {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil || filter.Offset < 0 {
+			return filter, NewHTTPError(c, h.log, fiber.StatusBadRequest, "offset must be non-negative integer", nil)
+		}
+	}
+	return filter, nil
+}
+
+// newAuditEvent creates the audit event of the action on the content of the owner.
+// The values are copied, because the event can be recorded after the request is reused.
+func newAuditEvent(c *fiber.Ctx, action string, ownerID uint) *model.AuditEvent {
+	var actorID uint
+	if user, ok := c.UserContext().Value("user").(*model.User); ok {
+		actorID = user.ID
+	}
+	return &model.AuditEvent{
+		CreatedAt: time.Now().UTC(),
+		Action:    action,
+		ActorID:   actorID,
+		OwnerID:   ownerID,
+		RequestID: utils.CopyString(requestID(c)),
+		IP:        c.IP(),
+	}
+}
+
+func newFileAuditEvent(c *fiber.Ctx, action string, fileInfo *model.File) *model.AuditEvent {
+	auditEvent := newAuditEvent(c, action, fileInfo.UserID)
+	fileID := fileInfo.ID
+	auditEvent.FileID = &fileID
+	return auditEvent
+}
+
+func newImageAuditEvent(c *fiber.Ctx, action string, image *model.Image) *model.AuditEvent {
+	auditEvent := newAuditEvent(c, action, image.UserID)
+	imageID := image.ID
+	auditEvent.ImageID = &imageID
+	return auditEvent
+}
+
+// recordAudit saves the audit event, the action is not failed if it cannot be saved
+func recordAudit(ctx context.Context, log *zap.SugaredLogger, auditDataStore data.AuditDataStore, auditEvent *model.AuditEvent) {
+	if err := auditDataStore.Create(ctx, auditEvent); err != nil {
+		log.Errorw("unable to save audit event", "error", err, "action", auditEvent.Action, "requestID", auditEvent.RequestID)
+	}
+}
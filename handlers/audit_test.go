@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type filterAuditDataStore struct {
+	filter data.AuditFilter
+	events []model.AuditEvent
+}
+
+func (store *filterAuditDataStore) Create(context.Context, *model.AuditEvent) error {
+	return nil
+}
+
+func (store *filterAuditDataStore) Find(_ context.Context, filter data.AuditFilter) ([]model.AuditEvent, error) {
+	store.filter = filter
+	return store.events, nil
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	store := &filterAuditDataStore{}
+	handler := NewAuditRouteHandler(zap.NewNop().Sugar(), store)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Get("/audit", handler.GetAuditEvents)
+
+	res, err := app.Test(httptest.NewRequest("GET", "/audit?user_id=3&file_id=abc&action=file.download&from=2022-01-01T00:00:00Z&to=2022-02-01T00:00:00Z&limit=1000&offset=10", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	require.Equal(t, data.AuditFilter{
+		UserID: 3,
+		FileID: "abc",
+		Action: model.AuditFileDownload,
+		From:   time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		Limit:  maxAuditLimit,
+		Offset: 10,
+	}, store.filter)
+
+	res, err = app.Test(httptest.NewRequest("GET", "/audit", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	require.Equal(t, data.AuditFilter{Limit: defaultAuditLimit}, store.filter)
+
+	for _, query := range []string{"user_id=abc", "from=yesterday", "from=2022-02-01T00:00:00Z&to=2022-01-01T00:00:00Z", "limit=0", "offset=-1"} {
+		res, err = app.Test(httptest.NewRequest("GET", "/audit?"+query, nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, res.StatusCode, query)
+	}
+}
+
+func TestGetOwnAuditEvents(t *testing.T) {
+	store := &filterAuditDataStore{}
+	handler := NewAuditRouteHandler(zap.NewNop().Sugar(), store)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Get("/audit", func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(c.UserContext(), "user", &model.User{ID: 5}))
+		return c.Next()
+	}, handler.GetOwnAuditEvents)
+
+	// The user cannot query the events of other users
+	res, err := app.Test(httptest.NewRequest("GET", "/audit?user_id=3", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	require.Equal(t, uint(5), store.filter.UserID)
+
+	// The actor and IP of other users are redacted
+	store.events = []model.AuditEvent{
+		{ID: 1, Action: model.AuditFileUpload, ActorID: 5, OwnerID: 5, IP: "10.0.0.5"},
+		{ID: 2, Action: model.AuditFileDownload, ActorID: 7, OwnerID: 5, IP: "10.0.0.7"},
+	}
+	res, err = app.Test(httptest.NewRequest("GET", "/audit", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	var events []model.AuditEvent
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&events))
+	require.Equal(t, []model.AuditEvent{
+		{ID: 1, Action: model.AuditFileUpload, ActorID: 5, OwnerID: 5, IP: "10.0.0.5"},
+		{ID: 2, Action: model.AuditFileDownload, OwnerID: 5},
+	}, events)
+}
@@ -17,20 +17,22 @@ import (
 )
 
 type AuthRouteHandler struct {
-	log           *zap.SugaredLogger
-	userDataStore data.UserDataStore
-	jwtManager    jwt.Manager
-	tokenManager  token.Manager
-	entrypoint    string
+	log            *zap.SugaredLogger
+	userDataStore  data.UserDataStore
+	jwtManager     jwt.Manager
+	tokenManager   token.Manager
+	entrypoint     string
+	auditDataStore data.AuditDataStore
 }
 
-func NewAuthRouteHandler(l *zap.SugaredLogger, userDataStore data.UserDataStore, jwtManager jwt.Manager, tokenManager token.Manager, entry string) *AuthRouteHandler {
+func NewAuthRouteHandler(l *zap.SugaredLogger, userDataStore data.UserDataStore, jwtManager jwt.Manager, tokenManager token.Manager, entry string, auditDataStore data.AuditDataStore) *AuthRouteHandler {
 	return &AuthRouteHandler{
-		log:           l,
-		userDataStore: userDataStore,
-		jwtManager:    jwtManager,
-		tokenManager:  tokenManager,
-		entrypoint:    entry,
+		log:            l,
+		userDataStore:  userDataStore,
+		jwtManager:     jwtManager,
+		tokenManager:   tokenManager,
+		entrypoint:     entry,
+		auditDataStore: auditDataStore,
 	}
 }
 
@@ -42,7 +44,7 @@ func (a AuthRouteHandler) OauthProviderCallback(c *fiber.Ctx) error {
 	}
 
 	// Check existing user
-	user, err := a.userDataStore.FindByProviderAndEmail(gothUser.Provider, gothUser.Email)
+	user, err := a.userDataStore.FindByProviderAndEmail(c.UserContext(), gothUser.Provider, gothUser.Email)
 	if err != nil {
 		a.log.Error("unable to find existing user\n" + err.Error())
 		return c.Redirect(a.entrypoint)
@@ -51,7 +53,7 @@ func (a AuthRouteHandler) OauthProviderCallback(c *fiber.Ctx) error {
 	if user == nil {
 		// Create new user
 		username := a.getUserName(gothUser.NickName, gothUser.FirstName, gothUser.Name, gothUser.Email)
-		user, err = a.userDataStore.Create(gothUser.Email, username, gothUser.Provider, gothUser.AvatarURL)
+		user, err = a.userDataStore.Create(c.UserContext(), gothUser.Email, username, gothUser.Provider, gothUser.AvatarURL)
 		if err != nil {
 			a.log.Error("unable to create user\n" + err.Error())
 			return c.Redirect(a.entrypoint)
@@ -94,11 +96,11 @@ func (a AuthRouteHandler) OauthProviderCallback(c *fiber.Ctx) error {
 func (a *AuthRouteHandler) GetUserInfo(c *fiber.Ctx) error {
 	user := c.UserContext().Value("user")
 	if user == nil {
-		return NewHTTPError(a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
+		return NewHTTPError(c, a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
 	}
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
+		return NewHTTPError(c, a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
 	}
 	return c.JSON(userModel)
 }
@@ -125,23 +127,24 @@ func (a *AuthRouteHandler) Logout(c *fiber.Ctx) error {
 func (a *AuthRouteHandler) GenerateAPIToken(c *fiber.Ctx) error {
 	user := c.UserContext().Value("user")
 	if user == nil {
-		return NewHTTPError(a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
+		return NewHTTPError(c, a.log, fiber.StatusUnauthorized, "Unauthorized", nil)
 	}
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(a.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
+		return NewHTTPError(c, a.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
 	}
 
 	apiKey, err := a.tokenManager.GenerateAPIToken()
 	if err != nil {
-		return NewHTTPError(a.log, fiber.StatusInternalServerError, "unable to generate api token", err)
+		return NewHTTPError(c, a.log, fiber.StatusInternalServerError, "unable to generate api token", err)
 	}
 
 	userModel.APIKey = apiKey
-	err = a.userDataStore.UpdateAPIKey(userModel.ID, apiKey)
+	err = a.userDataStore.UpdateAPIKey(c.UserContext(), userModel.ID, apiKey)
 	if err != nil {
-		return NewHTTPError(a.log, fiber.StatusInternalServerError, "unable to save new api token", err)
+		return NewHTTPError(c, a.log, fiber.StatusInternalServerError, "unable to save new api token", err)
 	}
+	recordAudit(c.UserContext(), a.log, a.auditDataStore, newAuditEvent(c, model.AuditAPIKeyGenerate, userModel.ID))
 
 	return c.Status(fiber.StatusCreated).JSON(userModel)
 }
@@ -158,9 +161,9 @@ func (a *AuthRouteHandler) ParseUser(c *fiber.Ctx) error {
 		}
 
 		// Get user from api-token
-		userModel, err := a.userDataStore.FindByAPIKey(apiKey)
+		userModel, err := a.userDataStore.FindByAPIKey(c.UserContext(), apiKey)
 		if err != nil {
-			return NewHTTPError(a.log, fiber.StatusInternalServerError, "unable to get user by api token", err)
+			return NewHTTPError(c, a.log, fiber.StatusInternalServerError, "unable to get user by api token", err)
 		}
 		if userModel == nil {
 			return c.Next()
@@ -180,9 +183,9 @@ func (a *AuthRouteHandler) ParseUser(c *fiber.Ctx) error {
 			a.clearCookie(c)
 			return c.Next()
 		}
-		user, err = a.userDataStore.FindById(uint(userIdInt))
+		user, err = a.userDataStore.FindById(c.UserContext(), uint(userIdInt))
 		if err != nil {
-			return NewHTTPError(a.log, fiber.StatusInternalServerError, "unable to get user by id", err)
+			return NewHTTPError(c, a.log, fiber.StatusInternalServerError, "unable to get user by id", err)
 		} else if user == nil {
 			a.clearCookie(c)
 			return c.Next()
@@ -196,7 +199,16 @@ func (a *AuthRouteHandler) ParseUser(c *fiber.Ctx) error {
 func (a *AuthRouteHandler) AuthenticatedOnly(c *fiber.Ctx) error {
 	user := c.UserContext().Value("user")
 	if user == nil {
-		return NewHTTPError(a.log, fiber.StatusUnauthorized, "Unauthenticated", nil)
+		return NewHTTPError(c, a.log, fiber.StatusUnauthorized, "Unauthenticated", nil)
+	}
+	return c.Next()
+}
+
+// AdminOnly must be used after AuthenticatedOnly
+func (a *AuthRouteHandler) AdminOnly(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok || userModel.Role != model.RoleAdmin {
+		return NewHTTPError(c, a.log, fiber.StatusForbidden, "Forbidden", nil)
 	}
 	return c.Next()
 }
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"strings"
+	"time"
+)
+
+// UploadBundle handlers
+// @Summary Upload multiple files as a bundle
+// @Description Upload multiple files that are shared with a single bundle token
+// @Tags Bundle
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param       files  formData  file  true  "Files"
+// @Param       slug  query  string  false  "Custom bundle token"
+// @Param       duration  query  string  false  "Store duration in day (7), Go duration (1h30m) or ISO-8601 duration (PT1H30M)"
+// @Param       expired_at  query  string  false  "Expiry time in RFC3339"
+// @Success      201  {object}  model.Bundle
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      413  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/bundle [post]
+func (h *FileRoutesHandler) UploadBundle(c *fiber.Ctx) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "unable to get files from form-data", err)
+	}
+	fileHeaders := form.File["files"]
+	if len(fileHeaders) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "At least one file must be provided", nil)
+	}
+
+	// Check total size
+	var totalSize int64
+	for _, fileHeader := range fileHeaders {
+		totalSize += fileHeader.Size
+	}
+	if totalSize > h.limits.MaxBundleSize {
+		return NewHTTPError(c, h.log, fiber.StatusRequestEntityTooLarge, "Files too large", nil)
+	}
+
+	// Check slug
+	t, err := h.tokenManager.GenerateFileToken()
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to generate bundle token", err)
+	}
+	bundleToken := strings.ToLower(c.Query("slug", t))
+	existingBundle, err := h.bundleDataStore.FindByToken(c.UserContext(), bundleToken)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get existing bundle token", err)
+	}
+	if existingBundle != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("%s slug is used", bundleToken), nil)
+	}
+
+	// Check store duration or expiry time
+	expiredAt := time.Now().UTC().Add(h.maxStoreDuration)
+	customExpiredAt, err := h.parseExpiredAt(c, c.Query("duration"), c.Query("expired_at"))
+	if err != nil {
+		return err
+	}
+	if customExpiredAt != nil {
+		expiredAt = *customExpiredAt
+	}
+
+	bundleID, err := h.tokenManager.GenerateFileID()
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to create bundle id", err)
+	}
+	bundle := &model.Bundle{
+		ID:        bundleID,
+		Token:     bundleToken,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		ExpiredAt: expiredAt,
+	}
+
+	// Get userId if exist
+	user := c.UserContext().Value("user")
+	if user != nil {
+		userModel, ok := user.(*model.User)
+		if !ok {
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		}
+		bundle.UserID = userModel.ID
+	}
+
+	for _, fileHeader := range fileHeaders {
+		fileID, err := h.tokenManager.GenerateFileID()
+		if err != nil {
+			h.deleteBundleFiles(c.UserContext(), bundle)
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to create file id", err)
+		}
+
+		fileInfo := model.File{
+			ID:        fileID,
+			Filename:  fileHeader.Filename,
+			FileSize:  uint64(fileHeader.Size),
+			CreatedAt: bundle.CreatedAt,
+			UpdatedAt: bundle.UpdatedAt,
+			ExpiredAt: bundle.ExpiredAt,
+			UserID:    bundle.UserID,
+			FileType:  fileHeader.Header.Get("Content-Type"),
+			Encrypted: true,
+			BundleID:  &bundle.ID,
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.deleteBundleFiles(c.UserContext(), bundle)
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to open file", err)
+		}
+		err = h.writeFile(c.UserContext(), &fileInfo, file)
+		_ = file.Close()
+		if err != nil {
+			h.deleteBundleFiles(c.UserContext(), bundle)
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to store the file", err)
+		}
+		bundle.Files = append(bundle.Files, fileInfo)
+		if err := h.deduplicate(c.UserContext(), &bundle.Files[len(bundle.Files)-1]); err != nil {
+			h.deleteBundleFiles(c.UserContext(), bundle)
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to deduplicate file", err)
+		}
+	}
+
+	if err := h.bundleDataStore.Create(c.UserContext(), bundle); err != nil {
+		h.deleteBundleFiles(c.UserContext(), bundle)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save bundle info to db", err)
+	}
+	for i := range bundle.Files {
+		recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileUpload, &bundle.Files[i]))
+		h.webhookDispatcher.Dispatch(bundle.UserID, webhook.NewFileEvent(webhook.EventFileCreated, &bundle.Files[i]))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bundle)
+}
+
+// GetBundleInfo handlers
+// @Summary Get bundle info
+// @Description Get the bundle and the list of its files
+// @Tags Bundle
+// @Produce  json
+// @Param        token       path      string      true  "Bundle Token"
+// @Success      200  {object}  model.Bundle
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/bundle/{token} [get]
+func (h *FileRoutesHandler) GetBundleInfo(c *fiber.Ctx) error {
+	bundle, err := h.bundleDataStore.FindByToken(c.UserContext(), strings.ToLower(c.Params("token")))
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get bundle query", err)
+	}
+	if bundle == nil {
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "Bundle not found", nil)
+	}
+	return c.JSON(bundle)
+}
+
+// GetOwnBundles handlers
+// @Summary List of uploaded bundle
+// @Description List all the bundles uploaded by the user with their files
+// @Tags Bundle
+// @Produce  json
+// @Success      200  {array} model.Bundle
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/bundle [get]
+func (h *FileRoutesHandler) GetOwnBundles(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	bundles, err := h.bundleDataStore.FindByUserID(c.UserContext(), userModel.ID)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get bundles", err)
+	}
+	return c.JSON(bundles)
+}
+
+// GetBundlePage handlers
+// @Summary Bundle landing page
+// @Description Page listing the files in the bundle
+// @Tags Bundle
+// @Produce  html
+// @Param        token       path      string      true  "Bundle Token"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /b/{token} [get]
+func (h *FileRoutesHandler) GetBundlePage(c *fiber.Ctx) error {
+	bundle, err := h.bundleDataStore.FindByToken(c.UserContext(), strings.ToLower(c.Params("token")))
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get bundle query", err)
+	}
+	if bundle == nil {
+		return c.Redirect(c.BaseURL() + "/404")
+	}
+
+	var page bytes.Buffer
+	if err := bundlePageTemplate.ExecuteTemplate(&page, "layout", bundle); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to render bundle page", err)
+	}
+	c.Set("Content-Type", fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(page.Bytes())
+}
+
+// GetBundleFile handlers
+// @Summary Download a file in the bundle
+// @Description Download a single file in the bundle
+// @Tags Bundle
+// @Produce  application/octet-stream
+// @Param        token       path      string      true  "Bundle Token"
+// @Param        fileID       path      string      true  "File ID"
+// @Param        inline       query      bool      false  "Display safe content types in the browser"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /b/{token}/{fileID} [get]
+func (h *FileRoutesHandler) GetBundleFile(c *fiber.Ctx) error {
+	bundle, err := h.bundleDataStore.FindByToken(c.UserContext(), strings.ToLower(c.Params("token")))
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get bundle query", err)
+	}
+	if bundle == nil {
+		return c.Redirect(c.BaseURL() + "/404")
+	}
+
+	fileID := c.Params("fileID")
+	for i := range bundle.Files {
+		if bundle.Files[i].ID == fileID {
+			return h.sendFile(c, &bundle.Files[i])
+		}
+	}
+	return c.Redirect(c.BaseURL() + "/404")
+}
+
+// GetBundleArchive handlers
+// @Summary Download the bundle as zip
+// @Description Download all files in the bundle as a zip archive
+// @Tags Bundle
+// @Produce  application/zip
+// @Param        token       path      string      true  "Bundle Token"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /b/{token}/zip [get]
+func (h *FileRoutesHandler) GetBundleArchive(c *fiber.Ctx) error {
+	bundle, err := h.bundleDataStore.FindByToken(c.UserContext(), strings.ToLower(c.Params("token")))
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get bundle query", err)
+	}
+	if bundle == nil {
+		return c.Redirect(c.BaseURL() + "/404")
+	}
+
+	return h.sendZipArchive(c, fmt.Sprintf("%s.zip", bundle.Token), bundle.Files)
+}
+
+// deleteBundleFiles removes the already written files of the bundle from storage, unless their content is still shared
+func (h *FileRoutesHandler) deleteBundleFiles(ctx context.Context, bundle *model.Bundle) {
+	for i := range bundle.Files {
+		if err := h.deleteFileContent(ctx, &bundle.Files[i]); err != nil {
+			h.log.Errorw("unable to delete bundle file on storage", "error", err, "fileID", bundle.Files[i].ID)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"github.com/thetkpark/cscms-temp-storage/service/throttle"
+	"github.com/thetkpark/cscms-temp-storage/service/token"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testEncryptionKey = "000102030405060708090A0B0C0D0E0FF0E0D0C0B0A090807060504030201000"
+
+type nopDispatcher struct{}
+
+func (nopDispatcher) Dispatch(uint, *webhook.Event) {}
+
+func (nopDispatcher) Deliver(context.Context, *model.Webhook, *webhook.Event) *model.WebhookDelivery {
+	return nil
+}
+
+// newTestFileRoutesHandler creates the handler with the database and storage in the temporary directory and deduplication enabled
+func newTestFileRoutesHandler(t *testing.T) (*FileRoutesHandler, *gorm.DB) {
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	log := zap.NewNop().Sugar()
+
+	fileDataStore, err := data.NewGormFileDataStore(db, time.Hour)
+	require.NoError(t, err)
+	bundleDataStore, err := data.NewGormBundleDataStore(db)
+	require.NoError(t, err)
+	userDataStore, err := data.NewGormUserDataStore(db)
+	require.NoError(t, err)
+	blobDataStore, err := data.NewGormBlobDataStore(db)
+	require.NoError(t, err)
+	auditDataStore, err := data.NewGormAuditDataStore(db)
+	require.NoError(t, err)
+	downloadDataStore, err := data.NewGormDownloadDataStore(db)
+	require.NoError(t, err)
+	storageManager, err := storage.NewDiskStorageManager(log, filepath.Join(dir, "files"))
+	require.NoError(t, err)
+	provider, err := encrypt.NewStaticKeyProvider("", testEncryptionKey, nil)
+	require.NoError(t, err)
+
+	handler := NewFileRoutesHandler(log, encrypt.NewEnvelopeEncryptionManager(log, provider, nil), fileDataStore, bundleDataStore, userDataStore, storageManager, token.NewNanoIDTokenManager(), nil, time.Hour, nil, blobDataStore, true, &event.NopSink{}, auditDataStore, downloadDataStore, nopDispatcher{}, UploadLimits{
+		MaxFileSize:   1 << 20,
+		MaxBundleSize: 1 << 20,
+	}, throttle.New(0, throttle.Limits{}))
+	return handler, db
+}
+
+func newMultipartRequest(t *testing.T, target string, field string, contents ...string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, content := range contents {
+		part, err := writer.CreateFormFile(field, fmt.Sprintf("file-%d.txt", i))
+		require.NoError(t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(fiber.MethodPost, target, &body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestUploadBundleDeduplicate(t *testing.T) {
+	handler, db := newTestFileRoutesHandler(t)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(c.UserContext(), "user", &model.User{ID: 3}))
+		return c.Next()
+	})
+	app.Post("/file", handler.UploadFile)
+	app.Post("/bundle", handler.UploadBundle)
+
+	res, err := app.Test(newMultipartRequest(t, "/file", "file", "same content"))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+	res, err = app.Test(newMultipartRequest(t, "/bundle", "files", "same content", "other content"))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+
+	// The bundle file with the same content shares the blob of the file uploaded alone
+	var file model.File
+	require.NoError(t, db.First(&file, "bundle_id IS NULL").Error)
+	require.NotNil(t, file.BlobID)
+	var bundleFiles []model.File
+	require.NoError(t, db.Where("bundle_id IS NOT NULL").Find(&bundleFiles).Error)
+	require.Len(t, bundleFiles, 2)
+	for _, bundleFile := range bundleFiles {
+		if bundleFile.Checksum == file.Checksum {
+			require.Equal(t, *file.BlobID, *bundleFile.BlobID)
+		} else {
+			require.NotEqual(t, *file.BlobID, *bundleFile.BlobID)
+		}
+	}
+	var blob model.Blob
+	require.NoError(t, db.First(&blob, "id = ?", *file.BlobID).Error)
+	require.Equal(t, uint(2), blob.RefCount)
+}
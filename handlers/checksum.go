@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseChecksum returns the hex SHA-256 checksum of the file supplied by the client.
+// The Digest header is not accepted, because it covers the whole multipart body instead of the file.
+// Empty string is returned if the client does not send any checksum.
+func parseChecksum(checksumHex string) (string, error) {
+	if len(checksumHex) == 0 {
+		return "", nil
+	}
+	checksum, err := hex.DecodeString(checksumHex)
+	if err != nil || len(checksum) != 32 {
+		return "", fmt.Errorf("checksum must be hex encoded SHA-256")
+	}
+	return hex.EncodeToString(checksum), nil
+}
+
+// setChecksumHeaders sets the Digest and ETag headers from the hex SHA-256 checksum
+func setChecksumHeaders(c *fiber.Ctx, checksum string) {
+	sum, err := hex.DecodeString(checksum)
+	if err != nil || len(sum) == 0 {
+		return
+	}
+	c.Set("Digest", fmt.Sprintf("sha-256=%s", base64.StdEncoding.EncodeToString(sum)))
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%s"`, checksum))
+}
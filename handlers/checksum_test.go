@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testChecksum = "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969"
+
+func TestParseChecksum(t *testing.T) {
+	checksum, err := parseChecksum(testChecksum)
+	require.NoError(t, err)
+	require.Equal(t, testChecksum, checksum)
+
+	checksum, err = parseChecksum("185F8DB32271FE25F561A6FC938B2E264306EC304EDA518007D1764826381969")
+	require.NoError(t, err)
+	require.Equal(t, testChecksum, checksum)
+
+	checksum, err = parseChecksum("")
+	require.NoError(t, err)
+	require.Empty(t, checksum)
+}
+
+func TestParseChecksumInvalid(t *testing.T) {
+	_, err := parseChecksum("not-hex")
+	require.Error(t, err)
+	_, err = parseChecksum("185f8db3")
+	require.Error(t, err)
+}
+
+func newChecksumUploadRequest(t *testing.T, content string, checksum string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "file.txt")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteField("checksum", checksum))
+	require.NoError(t, writer.Close())
+	req := httptest.NewRequest(fiber.MethodPost, "/file", &body)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return req
+}
+
+func TestUploadFileChecksum(t *testing.T) {
+	handler, _ := newTestFileRoutesHandler(t)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Post("/file", handler.UploadFile)
+
+	// The SHA-256 of hello
+	checksum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	res, err := app.Test(newChecksumUploadRequest(t, "hello", checksum))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+
+	res, err = app.Test(newChecksumUploadRequest(t, "other", checksum))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, res.StatusCode)
+
+	// The Digest header of the multipart body is not compared with the file
+	req := newMultipartRequest(t, "/file", "file", "hello")
+	req.Header.Set("Digest", "sha-256=GF+NsyJx/iX1Yab8k4suJkMG7DBO2lGAB9F2SCY4GWk=")
+	res, err = app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusCreated, res.StatusCode)
+}
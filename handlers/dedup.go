@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+)
+
+// deduplicate points the file to the blob with the same content uploaded by the same user
+// and removes the content that was just written. If there is no such blob, the written content becomes the new blob.
+// Only files of the same user are deduplicated, so no one can learn whether another user has the same file.
+// The file keeps its own content when deduplication is not possible.
+func (h *FileRoutesHandler) deduplicate(ctx context.Context, fileInfo *model.File) error {
+	if !h.dedup || fileInfo.UserID == 0 || len(fileInfo.Checksum) == 0 {
+		return nil
+	}
+
+	blob, err := h.blobDataStore.FindByChecksum(ctx, fileInfo.UserID, fileInfo.Checksum)
+	if err != nil {
+		return err
+	}
+	if blob == nil {
+		blobID := fileInfo.ID
+		err := h.blobDataStore.Create(ctx, &model.Blob{
+			ID:        blobID,
+			CreatedAt: fileInfo.CreatedAt,
+			UpdatedAt: fileInfo.CreatedAt,
+			UserID:    fileInfo.UserID,
+			Checksum:  fileInfo.Checksum,
+			Size:      fileInfo.FileSize,
+			RefCount:  1,
+		})
+		if err != nil {
+			// Same content may be uploaded at the same time
+			h.log.Infow("unable to create blob", "error", err, "fileID", fileInfo.ID)
+			return nil
+		}
+		fileInfo.BlobID = &blobID
+		return nil
+	}
+
+	// The file must be decrypted with the same data key as the other files of the blob
+	source, err := h.fileDataStore.FindByBlobID(ctx, blob.ID)
+	if err != nil {
+		return err
+	}
+	if source == nil || source.ClientEncrypted != fileInfo.ClientEncrypted {
+		return nil
+	}
+	if exist, err := h.storageManager.Exist(ctx, blob.ID); err != nil || !exist {
+		return err
+	}
+
+	if err := h.blobDataStore.AddReference(ctx, blob.ID); err != nil {
+		return err
+	}
+	if err := h.storageManager.DeleteFile(ctx, fileInfo.ID); err != nil {
+		h.log.Errorw("unable to delete duplicated file on storage", "error", err, "fileID", fileInfo.ID)
+	}
+	fileInfo.BlobID = &blob.ID
+	fileInfo.Encrypted = source.Encrypted
+	fileInfo.Nonce = source.Nonce
+	fileInfo.KeyID = source.KeyID
+	fileInfo.WrappedKey = source.WrappedKey
+	return nil
+}
+
+// deleteFileContent deletes the content of the file from storage
+// unless it is still shared with other files
+func (h *FileRoutesHandler) deleteFileContent(ctx context.Context, fileInfo *model.File) error {
+	return data.ReleaseContent(ctx, h.blobDataStore, h.storageManager, fileInfo)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var isoDurationRegex = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses the duration in whole day (7), Go duration syntax (1h30m) or ISO-8601 (PT1H30M).
+// The duration that does not fit in time.Duration is rejected instead of wrapping around.
+func parseDuration(s string) (time.Duration, error) {
+	// Whole day is kept for backward compatibility
+	if day, err := strconv.ParseInt(s, 10, 64); err == nil {
+		d, ok := multiplyDuration(day, 24*time.Hour)
+		if !ok {
+			return 0, fmt.Errorf("%s is too long", s)
+		}
+		return d, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	group := isoDurationRegex.FindStringSubmatch(s)
+	if group == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("%s is not a valid duration", s)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if len(group[i+1]) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(group[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid duration", s)
+		}
+		part, ok := multiplyDuration(n, unit)
+		if !ok || d > math.MaxInt64-part {
+			return 0, fmt.Errorf("%s is too long", s)
+		}
+		d += part
+	}
+	return d, nil
+}
+
+// multiplyDuration returns n units, or false if it overflows
+func multiplyDuration(n int64, unit time.Duration) (time.Duration, bool) {
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	testCases := map[string]time.Duration{
+		"7":       7 * 24 * time.Hour,
+		"90m":     90 * time.Minute,
+		"1h30m":   90 * time.Minute,
+		"PT1H":    time.Hour,
+		"PT45M":   45 * time.Minute,
+		"P1DT12H": 36 * time.Hour,
+		"P2W":     14 * 24 * time.Hour,
+		"PT30S":   30 * time.Second,
+	}
+	for input, expected := range testCases {
+		d, err := parseDuration(input)
+		require.NoError(t, err, input)
+		require.Equal(t, expected, d, input)
+	}
+}
+
+func TestParseInvalidDuration(t *testing.T) {
+	for _, input := range []string{"", "abc", "P", "PT", "P1Y", "P1M", "1d", "PT1.5H", "106752", "-106752", "P15251W", "P15250W7D", "PT9223372036854775807S", "99999999999999999999"} {
+		_, err := parseDuration(input)
+		require.Error(t, err, input)
+	}
+}
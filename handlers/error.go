@@ -5,9 +5,9 @@ import (
 	"go.uber.org/zap"
 )
 
-func NewHTTPError(log *zap.SugaredLogger, code int, message string, error error) error {
+func NewHTTPError(c *fiber.Ctx, log *zap.SugaredLogger, code int, message string, error error) error {
 	if code == fiber.StatusInternalServerError {
-		log.Errorw(message, "error", error.Error())
+		log.Errorw(message, "error", error.Error(), "requestID", requestID(c))
 	}
 	return fiber.NewError(code, message)
 }
@@ -15,4 +15,6 @@ func NewHTTPError(log *zap.SugaredLogger, code int, message string, error error)
 type ErrorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// RequestID is the ID of the request in the access log
+	RequestID string `json:"request_id"`
 }
@@ -1,14 +1,24 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/thetkpark/cscms-temp-storage/data"
 	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/e2e"
 	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"github.com/thetkpark/cscms-temp-storage/service/event"
+	"github.com/thetkpark/cscms-temp-storage/service/fetch"
+	"github.com/thetkpark/cscms-temp-storage/service/metrics"
 	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"github.com/thetkpark/cscms-temp-storage/service/throttle"
 	"github.com/thetkpark/cscms-temp-storage/service/token"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
 	"go.uber.org/zap"
 	"io"
 	"strconv"
@@ -20,19 +30,53 @@ type FileRoutesHandler struct {
 	log               *zap.SugaredLogger
 	encryptionManager encrypt.Manager
 	fileDataStore     data.FileDataStore
+	bundleDataStore   data.BundleDataStore
+	userDataStore     data.UserDataStore
 	storageManager    storage.FileManager
 	tokenManager      token.Manager
+	fetchManager      fetch.Manager
 	maxStoreDuration  time.Duration
+	permanentRoles    []string
+	remoteUploads     remoteUploads
+	blobDataStore     data.BlobDataStore
+	dedup             bool
+	downloadEvents    event.Sink
+	auditDataStore    data.AuditDataStore
+	downloadDataStore data.DownloadDataStore
+	webhookDispatcher webhook.Dispatcher
+	limits            UploadLimits
+	downloadThrottle  *throttle.Throttle
 }
 
-func NewFileRoutesHandler(log *zap.SugaredLogger, enc encrypt.Manager, data data.FileDataStore, store storage.FileManager, token token.Manager, duration time.Duration) *FileRoutesHandler {
+// UploadLimits is the maximum sizes of the content handled by FileRoutesHandler, the sizes are in bytes
+type UploadLimits struct {
+	MaxFileSize   int64
+	MaxBundleSize int64
+	MaxPasteSize  int64
+	// MaxArchiveFiles is the maximum number of files in a single archive download
+	MaxArchiveFiles int
+}
+
+func NewFileRoutesHandler(log *zap.SugaredLogger, enc encrypt.Manager, data data.FileDataStore, bundleData data.BundleDataStore, userData data.UserDataStore, store storage.FileManager, token token.Manager, fetchManager fetch.Manager, duration time.Duration, permanentRoles []string, blobData data.BlobDataStore, dedup bool, downloadEvents event.Sink, auditData data.AuditDataStore, downloadData data.DownloadDataStore, dispatcher webhook.Dispatcher, limits UploadLimits, downloadThrottle *throttle.Throttle) *FileRoutesHandler {
 	return &FileRoutesHandler{
 		log:               log,
 		encryptionManager: enc,
 		fileDataStore:     data,
+		bundleDataStore:   bundleData,
+		userDataStore:     userData,
 		storageManager:    store,
 		tokenManager:      token,
+		fetchManager:      fetchManager,
 		maxStoreDuration:  duration,
+		permanentRoles:    permanentRoles,
+		blobDataStore:     blobData,
+		dedup:             dedup,
+		downloadEvents:    downloadEvents,
+		auditDataStore:    auditData,
+		downloadDataStore: downloadData,
+		webhookDispatcher: dispatcher,
+		limits:            limits,
+		downloadThrottle:  downloadThrottle,
 	}
 }
 
@@ -43,6 +87,11 @@ func NewFileRoutesHandler(log *zap.SugaredLogger, enc encrypt.Manager, data data
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param       file  formData  file  true  "File"
+// @Param       slug  query  string  false  "Custom file token"
+// @Param       duration  query  string  false  "Store duration in day (7), Go duration (1h30m) or ISO-8601 duration (PT1H30M)"
+// @Param       expired_at  query  string  false  "Expiry time in RFC3339"
+// @Param       client_encrypted  query  bool  false  "The file is already encrypted by the client in DARE 2.0 format"
+// @Param       checksum  query  string  false  "Hex encoded SHA-256 of the file, the upload is rejected if it does not match. It can also be sent in the checksum form field."
 // @Success      201  {object}  model.File
 // @Failure      400  {object}  handlers.ErrorResponse
 // @Failure      500  {object}  handlers.ErrorResponse
@@ -50,161 +99,221 @@ func NewFileRoutesHandler(log *zap.SugaredLogger, enc encrypt.Manager, data data
 func (h *FileRoutesHandler) UploadFile(c *fiber.Ctx) error {
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to get file from form-data", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get file from form-data", err)
 	}
 
-	// Check image size (100MB)
-	if fileHeader.Size > 100<<20 {
-		return NewHTTPError(h.log, fiber.StatusRequestEntityTooLarge, "File too large", nil)
+	// Check file size
+	if fileHeader.Size > h.limits.MaxFileSize {
+		return NewHTTPError(c, h.log, fiber.StatusRequestEntityTooLarge, "File too large", nil)
 	}
-	// Check slug
-	t, err := h.tokenManager.GenerateFileToken()
+	checksum, err := parseChecksum(c.Query("checksum", c.FormValue("checksum")))
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to generate file token", err)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, err.Error(), nil)
 	}
-	fileToken := strings.ToLower(c.Query("slug", t))
-	// Check if slug is available
-	existingFile, err := h.fileDataStore.FindByToken(fileToken)
+	clientEncrypted := false
+	if clientEncryptedString := c.Query("client_encrypted"); len(clientEncryptedString) > 0 {
+		clientEncrypted, err = strconv.ParseBool(clientEncryptedString)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "client_encrypted must be boolean", nil)
+		}
+	}
+	fileInfo, err := h.newFileInfo(c, fileHeader.Filename, uint64(fileHeader.Size), fileHeader.Header.Get("Content-Type"))
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to get existing file token", err)
+		return err
 	}
-	if existingFile != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, fmt.Sprintf("%s slug is used", fileToken), nil)
+
+	// Open file from multipart form header
+	file, err := fileHeader.Open()
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to open file", err)
 	}
+	defer file.Close()
 
-	// Check store duration (in day)
-	storeDuration := h.maxStoreDuration
-	if dayString := c.Query("duration"); len(dayString) > 0 {
-		day, err := strconv.Atoi(dayString)
-		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "duration must be integer")
-		}
-		if float64(day*24) > h.maxStoreDuration.Hours() {
-			return NewHTTPError(h.log, fiber.StatusBadRequest, fmt.Sprintf("duration exceed maximum store duration (%v)", h.maxStoreDuration.Hours()/24), nil)
+	// Check the format of the end-to-end encrypted file, the server cannot decrypt it
+	var content io.Reader = file
+	if clientEncrypted {
+		header := make([]byte, e2e.HeaderSize)
+		if _, err := io.ReadFull(file, header); err != nil || !e2e.ValidHeader(header) {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("Client encrypted file must be in %s format", e2e.Format), nil)
 		}
-		storeDuration = time.Duration(day) * time.Hour * 24
+		content = io.MultiReader(bytes.NewReader(header), file)
+		fileInfo.ClientEncrypted = true
 	}
 
-	// Generate new file ID
-	fileId, err := h.tokenManager.GenerateFileID()
-	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to create file id", err)
+	// Write file content to disk
+	if err := h.writeFile(c.UserContext(), fileInfo, content); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to store the file", err)
 	}
-
-	// Create new fileInfo struct
-	fileInfo := &model.File{
-		ID:        fileId,
-		Token:     fileToken,
-		Nonce:     "",
-		Filename:  fileHeader.Filename,
-		FileSize:  uint64(fileHeader.Size),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		ExpiredAt: time.Now().UTC().Add(storeDuration),
-		Visited:   0,
-		UserID:    0,
-		FileType:  fileHeader.Header.Get("Content-Type"),
-		Encrypted: false,
+	if len(checksum) > 0 && checksum != fileInfo.Checksum {
+		if err := h.storageManager.DeleteFile(c.UserContext(), fileInfo.ID); err != nil {
+			h.log.Errorw("unable to delete mismatched file on storage", "error", err, "fileID", fileInfo.ID)
+		}
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Checksum does not match the uploaded file", nil)
+	}
+	if err := h.deduplicate(c.UserContext(), fileInfo); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to deduplicate file", err)
 	}
 
-	// Get userId if exist
-	user := c.UserContext().Value("user")
-	if user != nil {
-		userModel, ok := user.(*model.User)
-		if !ok {
-			return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	err = h.fileDataStore.Create(c.UserContext(), fileInfo)
+	if err != nil {
+		if err := h.deleteFileContent(c.UserContext(), fileInfo); err != nil {
+			h.log.Errorw("unable to delete file on storage", "error", err, "fileID", fileInfo.ID)
 		}
-		fileInfo.UserID = userModel.ID
-		fileInfo.Encrypted = true
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save file info to db", err)
 	}
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileUpload, fileInfo))
+	h.webhookDispatcher.Dispatch(fileInfo.UserID, webhook.NewFileEvent(webhook.EventFileCreated, fileInfo))
 
-	// Open file from multipart form header
-	var file io.Reader
-	file, err = fileHeader.Open()
+	return c.Status(fiber.StatusCreated).JSON(fileInfo)
+}
+
+// GetFilePage handlers
+// @Summary File landing page
+// @Description Page showing the file information with the download button and link preview meta tags
+// @Tags File
+// @Produce  html
+// @Param        token       path      string      true  "File Token"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /{token} [get]
+func (h *FileRoutesHandler) GetFilePage(c *fiber.Ctx) error {
+	t := strings.ToLower(c.Params("token"))
+
+	// Find file by token
+	fileInfo, err := h.fileDataStore.FindByToken(c.UserContext(), t)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to open file", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get file query", err)
+	}
+	if fileInfo == nil {
+		return c.Redirect(c.BaseURL() + "/404")
+	}
+	if fileInfo.IsPaste {
+		return c.Redirect(fmt.Sprintf("%s/p/%s", c.BaseURL(), fileInfo.Token))
 	}
 
-	if fileInfo.Encrypted {
-		// Encrypt the file
-		file, fileInfo.Nonce, err = h.encryptionManager.Encrypt(file)
+	// Get uploader display name
+	uploader := "Anonymous"
+	if fileInfo.UserID != 0 {
+		user, err := h.userDataStore.FindById(c.UserContext(), fileInfo.UserID)
 		if err != nil {
-			return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable encrypt the file", err)
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get uploader", err)
+		}
+		if user != nil {
+			uploader = user.Username
 		}
 	}
 
-	// Write file content to disk
-	if err := h.storageManager.WriteToNewFile(fileId, file); err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to write encrypted data to file", err)
+	contentType, previewable := inlineContentType(fileInfo.FileType)
+	previewable = previewable && !fileInfo.ClientEncrypted
+	page := filePage{
+		File:        fileInfo,
+		Uploader:    uploader,
+		Description: fmt.Sprintf("%s shared by %s, available until %s", formatSize(fileInfo.FileSize), uploader, fileInfo.ExpiredAt.UTC().Format("2 Jan 2006")),
+		URL:         fmt.Sprintf("%s/%s", c.BaseURL(), fileInfo.Token),
+		Previewable: previewable,
 	}
-
-	err = h.fileDataStore.Create(fileInfo)
-	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to save file info to db", err)
+	if isPermanent(fileInfo.ExpiredAt) {
+		page.Description = fmt.Sprintf("%s shared by %s, never expires", formatSize(fileInfo.FileSize), uploader)
+	}
+	if previewable && strings.HasPrefix(contentType, "image/") {
+		page.ImageURL = fmt.Sprintf("%s/%s/preview", c.BaseURL(), fileInfo.Token)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fileInfo)
+	var body bytes.Buffer
+	if err := filePageTemplate.ExecuteTemplate(&body, "layout", page); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to render file page", err)
+	}
+	c.Set("Content-Type", fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(body.Bytes())
 }
 
 // GetFile handlers
 // @Summary Download the file
-// @Description Access link to download the file
+// @Description Access link to download the file. Images, PDF, video, audio and plain text can be displayed in the browser with the inline mode.
 // @Tags File
 // @Produce  application/octet-stream
 // @Param        token       path      string      true  "File Token"
+// @Param        inline       query      bool      false  "Display safe content types in the browser"
 // @Success      200
+// @Header       200  {string}  Digest  "SHA-256 of the file (sha-256=base64)"
+// @Header       200  {string}  ETag  "Hex SHA-256 of the file"
+// @Success      304
 // @Failure      500  {object}  handlers.ErrorResponse
-// @Router /{token} [get]
+// @Router /{token}/download [get]
 func (h *FileRoutesHandler) GetFile(c *fiber.Ctx) error {
 	t := strings.ToLower(c.Params("token"))
 
 	// Find file by token
-	fileInfo, err := h.fileDataStore.FindByToken(t)
+	fileInfo, err := h.fileDataStore.FindByToken(c.UserContext(), t)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to get file query", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get file query", err)
 	}
 	if fileInfo == nil {
 		return c.Redirect(c.BaseURL() + "/404")
 	}
 
-	// Check if file still exist on storage
-	if exist, err := h.storageManager.Exist(fileInfo.ID); !exist {
-		if err == nil {
-			// File is not exist anymore
-			return c.Redirect(c.BaseURL() + "/404")
-		}
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to check if file exist", err)
-	}
+	return h.sendFile(c, fileInfo)
+}
 
-	// Get encrypted file from storage manager
-	file, err := h.storageManager.OpenFile(fileInfo.ID)
+// GetFilePreview handlers
+// @Summary Preview the image
+// @Description Display the image of the file page preview. The preview is not counted as the download of the file.
+// @Tags File
+// @Produce  image/png,image/jpeg,image/gif,image/webp
+// @Param        token       path      string      true  "File Token"
+// @Success      200
+// @Success      304
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /{token}/preview [get]
+func (h *FileRoutesHandler) GetFilePreview(c *fiber.Ctx) error {
+	t := strings.ToLower(c.Params("token"))
+
+	// Find file by token
+	fileInfo, err := h.fileDataStore.FindByToken(c.UserContext(), t)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to open encrypted file", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get file query", err)
+	}
+	if fileInfo == nil {
+		return c.Redirect(c.BaseURL() + "/404")
+	}
+	contentType, previewable := inlineContentType(fileInfo.FileType)
+	if !previewable || fileInfo.ClientEncrypted || !strings.HasPrefix(contentType, "image/") {
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "Preview not found", nil)
 	}
 
-	if fileInfo.Encrypted {
-		// Decrypt file if encrypted
-		file, err = h.encryptionManager.Decrypt(file, fileInfo.Nonce)
-		if err != nil {
-			return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to decrypt", err)
+	if exist, err := h.storageManager.Exist(c.UserContext(), fileInfo.StorageKey()); !exist {
+		if err == nil {
+			return c.Redirect(c.BaseURL() + "/404")
 		}
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to check if file exist", err)
+	}
+	if len(fileInfo.Checksum) > 0 && c.Get(fiber.HeaderIfNoneMatch) == fmt.Sprintf(`"%s"`, fileInfo.Checksum) {
+		setChecksumHeaders(c, fileInfo.Checksum)
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
-	// Increase visited count
-	err = h.fileDataStore.IncreaseVisited(fileInfo.ID)
+	ctx, cancel := context.WithCancel(c.UserContext())
+	file, err := h.openFile(ctx, fileInfo)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to increase count", err)
+		cancel()
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to open the file", err)
+	}
+	// Unlike sendFile, the preview is not recorded as the download
+	if c.Method() != fiber.MethodHead {
+		user, _ := c.UserContext().Value("user").(*model.User)
+		file = h.downloadThrottle.Reader(ctx, file, user)
 	}
 
-	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileInfo.Filename))
-	c.Set("Content-Type", "application/octet-stream")
-
-	return c.SendStream(file, int(fileInfo.FileSize))
+	c.Set("X-Content-Type-Options", "nosniff")
+	setChecksumHeaders(c, fileInfo.Checksum)
+	setInlineHeaders(c, contentType, fileInfo.Filename)
+	return c.SendStream(&streamReadCloser{ReadCloser: metrics.NewTransferReader(file, metrics.Download), cancel: cancel}, int(fileInfo.FileSize))
 }
 
 // GetOwnFiles handlers
 // @Summary List of uploaded file
-// @Description List all the upload file by the user
+// @Description List all the upload file by the user, the files of the bundles are listed with their bundle
 // @Tags File
 // @Produce  json
 // @Success      200  {array} model.File
@@ -215,12 +324,12 @@ func (h *FileRoutesHandler) GetOwnFiles(c *fiber.Ctx) error {
 	user := c.UserContext().Value("user")
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
 	}
 
-	files, err := h.fileDataStore.FindByUserID(userModel.ID)
+	files, err := h.fileDataStore.FindByUserID(c.UserContext(), userModel.ID)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "Unable to find files by user ID", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to find files by user ID", err)
 	}
 
 	return c.JSON(files)
@@ -229,25 +338,25 @@ func (h *FileRoutesHandler) GetOwnFiles(c *fiber.Ctx) error {
 func (h *FileRoutesHandler) IsOwnFile(c *fiber.Ctx) error {
 	fileId := c.Params("fileID", "")
 	if len(fileId) == 0 {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "File ID must be provided", nil)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "File ID must be provided", nil)
 	}
 
 	// Get userId
 	user := c.UserContext().Value("user")
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
 	}
 
-	file, err := h.fileDataStore.FindByID(fileId)
+	file, err := h.fileDataStore.FindByID(c.UserContext(), fileId)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to find file by id", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to find file by id", err)
 	}
 	if file == nil || file.ExpiredAt.UTC().Before(time.Now().UTC()) {
-		return NewHTTPError(h.log, fiber.StatusNotFound, "File not found", nil)
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "File not found", nil)
 	}
 	if file.UserID != userModel.ID {
-		return NewHTTPError(h.log, fiber.StatusForbidden, "Forbidden", nil)
+		return NewHTTPError(c, h.log, fiber.StatusForbidden, "Forbidden", nil)
 	}
 
 	c.SetUserContext(context.WithValue(c.UserContext(), "file", file))
@@ -269,61 +378,353 @@ func (h *FileRoutesHandler) IsOwnFile(c *fiber.Ctx) error {
 func (h *FileRoutesHandler) DeleteFile(c *fiber.Ctx) error {
 	fileModel, ok := c.UserContext().Value("file").(*model.File)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
 	}
 
 	// Delete file record in db
-	err := h.fileDataStore.DeleteByID(fileModel.ID)
+	err := h.fileDataStore.DeleteByID(c.UserContext(), fileModel.ID)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to delete file record in db", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to delete file record in db", err)
 	}
 
-	// Delete file on storage
-	err = h.storageManager.DeleteFile(fileModel.ID)
+	// Delete file on storage, shared content is only deleted with the last file
+	err = h.deleteFileContent(c.UserContext(), fileModel)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to delete file on storage", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to delete file on storage", err)
 	}
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileDelete, fileModel))
+	h.webhookDispatcher.Dispatch(fileModel.UserID, webhook.NewFileEvent(webhook.EventFileDeleted, fileModel))
 
 	return c.JSON(fileModel)
 }
 
-// EditToken handlers
-// @Summary Edit file token
-// @Description Edit the file token/slug
+// EditFile handlers
+// @Summary Edit file
+// @Description Edit the file token/slug or expiry. Keeping the file permanently requires a permitted role
 // @Tags File
 // @Produce  json
 // @Param        fileID       path      string      true  "File ID"
-// @Param        token       query      string      true  "New file token"
+// @Param        token       query      string      false  "New file token"
+// @Param        duration       query      string      false  "New store duration counted from now, in day (7), Go duration (1h30m) or ISO-8601 duration (PT1H30M)"
+// @Param        expired_at       query      string      false  "New expiry time in RFC3339"
+// @Param        permanent       query      bool      false  "Keep the file forever, or false to keep it until the new duration, expired_at or the maximum store duration from now"
 // @Success      200  {object} model.File
 // @Failure      400  {object}  handlers.ErrorResponse
 // @Failure      401  {object}  handlers.ErrorResponse
 // @Failure      403  {object}  handlers.ErrorResponse
 // @Failure      500  {object}  handlers.ErrorResponse
 // @Router /api/file/{fileID} [patch]
-func (h *FileRoutesHandler) EditToken(c *fiber.Ctx) error {
+func (h *FileRoutesHandler) EditFile(c *fiber.Ctx) error {
 	fileModel, ok := c.UserContext().Value("file").(*model.File)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse file model", fmt.Errorf("unable to parse file model"))
+	}
+
+	newToken := strings.ToLower(c.Query("token", ""))
+	durationString := c.Query("duration", "")
+	expiredAtString := c.Query("expired_at", "")
+	permanentString := c.Query("permanent", "")
+	if len(newToken) == 0 && len(durationString) == 0 && len(expiredAtString) == 0 && len(permanentString) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "New token, duration, expired_at or permanent must be provided", nil)
+	}
+
+	// Validate the new expiry before changing anything
+	var newExpiredAt *time.Time
+	if len(permanentString) > 0 {
+		permanent, err := strconv.ParseBool(permanentString)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "permanent must be boolean", nil)
+		}
+		if permanent {
+			if len(durationString) > 0 || len(expiredAtString) > 0 {
+				return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Only one of permanent, duration or expired_at can be provided", nil)
+			}
+			userModel, ok := c.UserContext().Value("user").(*model.User)
+			if !ok {
+				return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+			}
+			if !h.canKeepPermanently(userModel) {
+				return NewHTTPError(c, h.log, fiber.StatusForbidden, "Not allowed to keep file permanently", nil)
+			}
+			newExpiredAt = &model.PermanentExpiry
+		} else if len(durationString) == 0 && len(expiredAtString) == 0 {
+			// Without the new expiry, the file is kept for the maximum store duration from now
+			expiredAt := time.Now().UTC().Add(h.maxStoreDuration)
+			newExpiredAt = &expiredAt
+		}
+	}
+	if newExpiredAt == nil {
+		expiredAt, err := h.parseExpiredAt(c, durationString, expiredAtString)
+		if err != nil {
+			return err
+		}
+		newExpiredAt = expiredAt
+	}
+
+	if len(newToken) > 0 {
+		existingFile, err := h.fileDataStore.FindByToken(c.UserContext(), newToken)
+		if existingFile != nil || reservedTokens[newToken] || h.remoteUploads.tokenReserved(newToken) {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, "New token is in used", nil)
+		}
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to query existing file with token", err)
+		}
+
+		auditEvent := newFileAuditEvent(c, model.AuditFileTokenEdit, fileModel)
+		auditEvent.Detail = fmt.Sprintf("%s -> %s", fileModel.Token, newToken)
+		fileModel.Token = newToken
+		err = h.fileDataStore.UpdateToken(c.UserContext(), fileModel.ID, newToken)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save edited file model", err)
+		}
+		recordAudit(c.UserContext(), h.log, h.auditDataStore, auditEvent)
+	}
+
+	if newExpiredAt != nil {
+		auditEvent := newFileAuditEvent(c, model.AuditFileExpiryEdit, fileModel)
+		auditEvent.Detail = fmt.Sprintf("%s -> %s", fileModel.ExpiredAt.UTC().Format(time.RFC3339), newExpiredAt.UTC().Format(time.RFC3339))
+		fileModel.ExpiredAt = *newExpiredAt
+		err := h.fileDataStore.UpdateExpiredAt(c.UserContext(), fileModel.ID, *newExpiredAt)
+		if err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save file expiry", err)
+		}
+		recordAudit(c.UserContext(), h.log, h.auditDataStore, auditEvent)
+	}
+
+	return c.JSON(fileModel)
+}
+
+// parseExpiredAt returns the expiry time from either the store duration or the absolute expiry time.
+// It returns nil if neither is provided.
+func (h *FileRoutesHandler) parseExpiredAt(c *fiber.Ctx, durationString, expiredAtString string) (*time.Time, error) {
+	if len(durationString) == 0 && len(expiredAtString) == 0 {
+		return nil, nil
+	}
+	if len(durationString) > 0 && len(expiredAtString) > 0 {
+		return nil, NewHTTPError(c, h.log, fiber.StatusBadRequest, "Only one of duration or expired_at can be provided", nil)
 	}
 
-	newToken := c.Query("token", "")
-	if len(newToken) == 0 {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "New Token must be provided", nil)
+	now := time.Now().UTC()
+	var expiredAt time.Time
+	if len(durationString) > 0 {
+		duration, err := parseDuration(durationString)
+		if err != nil {
+			return nil, NewHTTPError(c, h.log, fiber.StatusBadRequest, "duration must be in day, Go duration or ISO-8601 duration", nil)
+		}
+		// The duration is compared before it is added, so the huge duration cannot wrap the expiry time
+		if duration > h.maxStoreDuration {
+			return nil, h.maxStoreDurationError(c)
+		}
+		expiredAt = now.Add(duration)
+	} else {
+		t, err := time.Parse(time.RFC3339, expiredAtString)
+		if err != nil {
+			return nil, NewHTTPError(c, h.log, fiber.StatusBadRequest, "expired_at must be in RFC3339 format", nil)
+		}
+		expiredAt = t.UTC()
 	}
 
-	existingFile, err := h.fileDataStore.FindByToken(newToken)
-	if existingFile != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "New token is in used", nil)
+	if !expiredAt.After(now) {
+		return nil, NewHTTPError(c, h.log, fiber.StatusBadRequest, "expiry must be in the future", nil)
 	}
+	if expiredAt.After(now.Add(h.maxStoreDuration)) {
+		return nil, h.maxStoreDurationError(c)
+	}
+	return &expiredAt, nil
+}
+
+func (h *FileRoutesHandler) maxStoreDurationError(c *fiber.Ctx) error {
+	return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("duration exceed maximum store duration (%v)", h.maxStoreDuration.Hours()/24), nil)
+}
+
+func (h *FileRoutesHandler) canKeepPermanently(user *model.User) bool {
+	for _, role := range h.permanentRoles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// reservedTokens are the first path segments of the other routes, the file page with these tokens can not be reached
+var reservedTokens = map[string]bool{
+	"404":     true,
+	"api":     true,
+	"auth":    true,
+	"b":       true,
+	"healthz": true,
+	"metrics": true,
+	"p":       true,
+	"readyz":  true,
+	"swagger": true,
+}
+
+// newFileInfo creates the file model from the slug, duration and user of the request
+func (h *FileRoutesHandler) newFileInfo(c *fiber.Ctx, filename string, fileSize uint64, fileType string) (*model.File, error) {
+	// Check slug
+	t, err := h.tokenManager.GenerateFileToken()
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to query existing file with token", err)
+		return nil, NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to generate file token", err)
+	}
+	fileToken := strings.ToLower(utils.CopyString(c.Query("slug", t)))
+	// Check if slug is available
+	existingFile, err := h.fileDataStore.FindByToken(c.UserContext(), fileToken)
+	if err != nil {
+		return nil, NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get existing file token", err)
+	}
+	if existingFile != nil || reservedTokens[fileToken] || h.remoteUploads.tokenReserved(fileToken) {
+		return nil, NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("%s slug is used", fileToken), nil)
 	}
 
-	fileModel.Token = newToken
-	err = h.fileDataStore.UpdateToken(fileModel.ID, newToken)
+	// Check store duration or expiry time
+	expiredAt := time.Now().UTC().Add(h.maxStoreDuration)
+	customExpiredAt, err := h.parseExpiredAt(c, c.Query("duration"), c.Query("expired_at"))
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to save edited file model", err)
+		return nil, err
+	}
+	if customExpiredAt != nil {
+		expiredAt = *customExpiredAt
 	}
 
-	return c.JSON(fileModel)
+	// Generate new file ID
+	fileId, err := h.tokenManager.GenerateFileID()
+	if err != nil {
+		return nil, NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to create file id", err)
+	}
+
+	// Create new fileInfo struct
+	fileInfo := &model.File{
+		ID:        fileId,
+		Token:     fileToken,
+		Nonce:     "",
+		Filename:  filename,
+		FileSize:  fileSize,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		ExpiredAt: expiredAt,
+		Visited:   0,
+		UserID:    0,
+		FileType:  fileType,
+		Encrypted: true,
+	}
+
+	// Get userId if exist
+	user := c.UserContext().Value("user")
+	if user != nil {
+		userModel, ok := user.(*model.User)
+		if !ok {
+			return nil, NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		}
+		fileInfo.UserID = userModel.ID
+	}
+
+	return fileInfo, nil
+}
+
+// writeFile encrypts the content and writes it to storage.
+// The SHA-256 checksum of the content is computed while it is written.
+func (h *FileRoutesHandler) writeFile(ctx context.Context, fileInfo *model.File, file io.Reader) error {
+	hash := sha256.New()
+	body := metrics.NewTransferReader(io.NopCloser(file), metrics.Upload)
+	defer body.Close()
+
+	// Encrypt the file
+	file, metadata, err := h.encryptionManager.Encrypt(ctx, io.TeeReader(body, hash))
+	if err != nil {
+		return fmt.Errorf("unable to encrypt the file: %w", err)
+	}
+	fileInfo.Nonce = metadata.Nonce
+	fileInfo.KeyID = metadata.KeyID
+	fileInfo.WrappedKey = metadata.WrappedKey
+	fileInfo.Encrypted = true
+
+	// Write file content to disk
+	if err := h.storageManager.WriteToNewFile(ctx, fileInfo.ID, file); err != nil {
+		return fmt.Errorf("unable to write encrypted data to file: %w", err)
+	}
+	fileInfo.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// openFile opens the file content from storage and decrypts it if needed
+func (h *FileRoutesHandler) openFile(ctx context.Context, fileInfo *model.File) (io.ReadCloser, error) {
+	// Get encrypted file from storage manager
+	file, err := h.storageManager.OpenFile(ctx, fileInfo.StorageKey())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open encrypted file: %w", err)
+	}
+	closer, ok := file.(io.Closer)
+	if !ok {
+		closer = io.NopCloser(file)
+	}
+
+	if fileInfo.Encrypted {
+		// Decrypt file if encrypted
+		file, err = h.encryptionManager.Decrypt(ctx, file, &encrypt.Metadata{Nonce: fileInfo.Nonce, KeyID: fileInfo.KeyID, WrappedKey: fileInfo.WrappedKey})
+		if err != nil {
+			_ = closer.Close()
+			return nil, fmt.Errorf("unable to decrypt: %w", err)
+		}
+	}
+
+	return &fileReadCloser{Reader: file, Closer: closer}, nil
+}
+
+// sendFile streams the file content to the client as attachment, or inline for safe content types in the inline mode
+func (h *FileRoutesHandler) sendFile(c *fiber.Ctx, fileInfo *model.File) error {
+	// Check if file still exist on storage
+	if exist, err := h.storageManager.Exist(c.UserContext(), fileInfo.StorageKey()); !exist {
+		if err == nil {
+			// File is not exist anymore
+			return c.Redirect(c.BaseURL() + "/404")
+		}
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to check if file exist", err)
+	}
+
+	// The client already has the same content
+	if len(fileInfo.Checksum) > 0 && c.Get(fiber.HeaderIfNoneMatch) == fmt.Sprintf(`"%s"`, fileInfo.Checksum) {
+		setChecksumHeaders(c, fileInfo.Checksum)
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	// The stream is read after the handler returns, its context is cancelled when the stream is closed
+	ctx, cancel := context.WithCancel(c.UserContext())
+	file, err := h.openFile(ctx, fileInfo)
+	if err != nil {
+		cancel()
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to open the file", err)
+	}
+	// The download is recorded when the stream is closed, the body of HEAD request is not sent
+	if c.Method() != fiber.MethodHead {
+		file = h.newDownloadReader(ctx, fileInfo, newDownloadSource(c), file)
+		user, _ := c.UserContext().Value("user").(*model.User)
+		file = h.downloadThrottle.Reader(ctx, file, user)
+	}
+
+	c.Set("X-Content-Type-Options", "nosniff")
+	setChecksumHeaders(c, fileInfo.Checksum)
+	if fileInfo.ClientEncrypted {
+		// Only the client with the key from the URL fragment can decrypt the content
+		c.Set("X-Client-Encrypted", "true")
+		c.Set("X-Client-Encryption-Format", e2e.Format)
+		if size, err := e2e.DecryptedSize(fileInfo.FileSize); err == nil {
+			c.Set("X-Decrypted-Size", strconv.FormatUint(size, 10))
+		}
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileInfo.Filename))
+		c.Set("Content-Type", "application/octet-stream")
+	} else if contentType, ok := inlineContentType(fileInfo.FileType); ok && wantInline(c) {
+		setInlineHeaders(c, contentType, fileInfo.Filename)
+	} else {
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileInfo.Filename))
+		c.Set("Content-Type", "application/octet-stream")
+	}
+
+	h.sendDownloadEvent(newDownloadEvent(c, fileInfo))
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileDownload, fileInfo))
+	return c.SendStream(&streamReadCloser{ReadCloser: metrics.NewTransferReader(file, metrics.Download), cancel: cancel}, int(fileInfo.FileSize))
+}
+
+type fileReadCloser struct {
+	io.Reader
+	io.Closer
 }
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func createTestFile(t *testing.T, handler *FileRoutesHandler, id string, token string, expiredAt time.Time) *model.File {
+	file := &model.File{
+		ID:        id,
+		Token:     token,
+		Filename:  id + ".txt",
+		FileSize:  5,
+		FileType:  fiber.MIMETextPlain,
+		ExpiredAt: expiredAt,
+	}
+	require.NoError(t, handler.fileDataStore.Create(context.Background(), file))
+	return file
+}
+
+func TestEditFileTokenLowercase(t *testing.T) {
+	handler, _ := newTestFileRoutesHandler(t)
+	file := createTestFile(t, handler, "file", "old-token", time.Now().Add(time.Hour))
+	createTestFile(t, handler, "other", "taken", time.Now().Add(time.Hour))
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Patch("/:fileID", func(c *fiber.Ctx) error {
+		fileModel, err := handler.fileDataStore.FindByID(c.UserContext(), c.Params("fileID"))
+		require.NoError(t, err)
+		c.SetUserContext(context.WithValue(c.UserContext(), "file", fileModel))
+		return c.Next()
+	}, handler.EditFile)
+
+	// The token is compared with the existing and reserved tokens in lowercase
+	res, err := app.Test(httptest.NewRequest(fiber.MethodPatch, "/file?token=TAKEN", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, res.StatusCode)
+	res, err = app.Test(httptest.NewRequest(fiber.MethodPatch, "/file?token=API", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, res.StatusCode)
+
+	res, err = app.Test(httptest.NewRequest(fiber.MethodPatch, "/file?token=New-Token", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	fileModel, err := handler.fileDataStore.FindByID(context.Background(), file.ID)
+	require.NoError(t, err)
+	require.Equal(t, "new-token", fileModel.Token)
+}
+
+func TestGetFilePagePermanent(t *testing.T) {
+	handler, _ := newTestFileRoutesHandler(t)
+	createTestFile(t, handler, "file", "permanent", model.PermanentExpiry)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Get("/:token", handler.GetFilePage)
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/permanent", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "Never expires")
+	require.Contains(t, string(body), "never expires")
+	require.NotContains(t, string(body), "9999")
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/service/health"
+	"go.uber.org/zap"
+)
+
+type HealthRouteHandler struct {
+	log       *zap.SugaredLogger
+	liveness  *health.Health
+	readiness *health.Health
+}
+
+func NewHealthRouteHandler(log *zap.SugaredLogger, liveness *health.Health, readiness *health.Health) *HealthRouteHandler {
+	return &HealthRouteHandler{
+		log:       log,
+		liveness:  liveness,
+		readiness: readiness,
+	}
+}
+
+// Liveness handlers
+// @Summary Liveness probe
+// @Description Check the dependencies that restarting the server can recover, e.g. the writability of the storage path
+// @Tags Health
+// @Produce  json
+// @Success      200  {object}  health.Report
+// @Failure      503  {object}  health.Report
+// @Router /healthz [get]
+func (h *HealthRouteHandler) Liveness(c *fiber.Ctx) error {
+	return h.sendHealthReport(c, h.liveness.Run(c.UserContext()))
+}
+
+// Readiness handlers
+// @Summary Readiness probe
+// @Description Check all dependencies: database, storage path, image storage and free disk space
+// @Tags Health
+// @Produce  json
+// @Success      200  {object}  health.Report
+// @Failure      503  {object}  health.Report
+// @Router /readyz [get]
+func (h *HealthRouteHandler) Readiness(c *fiber.Ctx) error {
+	return h.sendHealthReport(c, h.readiness.Run(c.UserContext()))
+}
+
+// sendHealthReport sends only the status of the dependencies, the errors are logged
+func (h *HealthRouteHandler) sendHealthReport(c *fiber.Ctx, report *health.Report) error {
+	status := fiber.StatusOK
+	if report.Status != health.StatusUp {
+		status = fiber.StatusServiceUnavailable
+	}
+	for name, result := range report.Checks {
+		if result.Status != health.StatusUp {
+			h.log.Warnw("health check failed", "check", name, "error", result.Error, "path", c.Path())
+		}
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(report)
+}
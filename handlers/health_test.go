@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"github.com/thetkpark/cscms-temp-storage/service/health"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthRouteHandler(t *testing.T) {
+	up := health.CheckerFunc(func(context.Context) error { return nil })
+	down := health.CheckerFunc(func(context.Context) error { return errors.New("connection refused") })
+	handler := NewHealthRouteHandler(zap.NewNop().Sugar(),
+		health.New(time.Second).Add("storage", up),
+		health.New(time.Second).Add("storage", up).Add("database", down),
+	)
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Get("/healthz", handler.Liveness)
+	app.Get("/readyz", handler.Readiness)
+
+	res, err := app.Test(httptest.NewRequest("GET", "/healthz", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+
+	res, err = app.Test(httptest.NewRequest("GET", "/readyz", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusServiceUnavailable, res.StatusCode)
+	var report health.Report
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	require.Equal(t, health.StatusDown, report.Status)
+	require.Equal(t, health.StatusUp, report.Checks["storage"].Status)
+	require.Equal(t, health.StatusDown, report.Checks["database"].Status)
+	// The error is only logged
+	require.Empty(t, report.Checks["database"].Error)
+}
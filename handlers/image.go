@@ -8,6 +8,7 @@ import (
 	"github.com/thetkpark/cscms-temp-storage/data/model"
 	"github.com/thetkpark/cscms-temp-storage/service/storage"
 	"github.com/thetkpark/cscms-temp-storage/service/token"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
 	"go.uber.org/zap"
 	"regexp"
 	"strconv"
@@ -19,14 +20,20 @@ type ImageRouteHandler struct {
 	imageDataStore    data.ImageDataStore
 	imageStoreManager storage.ImageManager
 	tokenManager      token.Manager
+	auditDataStore    data.AuditDataStore
+	webhookDispatcher webhook.Dispatcher
+	maxImageSize      int64
 }
 
-func NewImageRouteHandler(log *zap.SugaredLogger, imgDataStore data.ImageDataStore, store storage.ImageManager, token token.Manager) *ImageRouteHandler {
+func NewImageRouteHandler(log *zap.SugaredLogger, imgDataStore data.ImageDataStore, store storage.ImageManager, token token.Manager, auditData data.AuditDataStore, dispatcher webhook.Dispatcher, maxImageSize int64) *ImageRouteHandler {
 	return &ImageRouteHandler{
 		log:               log,
 		imageDataStore:    imgDataStore,
 		imageStoreManager: store,
 		tokenManager:      token,
+		auditDataStore:    auditData,
+		webhookDispatcher: dispatcher,
+		maxImageSize:      maxImageSize,
 	}
 }
 
@@ -46,34 +53,34 @@ func (h *ImageRouteHandler) UploadImage(c *fiber.Ctx) error {
 	// Get image file from Form
 	fileHeader, err := c.FormFile("image")
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "Unable to get file from multipart/form-data", err)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Unable to get file from multipart/form-data", err)
 	}
 
-	// Check image size (5MB)
-	if fileHeader.Size > 5<<20 {
-		return NewHTTPError(h.log, fiber.StatusRequestEntityTooLarge, "Image file too large", nil)
+	// Check image size
+	if fileHeader.Size > h.maxImageSize {
+		return NewHTTPError(c, h.log, fiber.StatusRequestEntityTooLarge, "Image file too large", nil)
 	}
 	// Check image format and get extension
 	imageMimeType := fileHeader.Header.Get("Content-Type")
 	fileExtension, err := h.validateFileFormat(imageMimeType, fileHeader.Filename)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "Invalid file extension", err)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Invalid file extension", err)
 	}
 	imageToken, err := h.tokenManager.GenerateImageToken()
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "Unable to generate image token", err)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Unable to generate image token", err)
 	}
 	imagePath := fmt.Sprintf("%s.%s", imageToken, fileExtension)
 
 	// Open the file
 	file, err := fileHeader.Open()
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "Unable to open file from the fileHeader", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to open file from the fileHeader", err)
 	}
 
 	// Upload the image to storage
-	if err := h.imageStoreManager.UploadImage(imagePath, imageMimeType, file); err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "Unable to upload image", err)
+	if err := h.imageStoreManager.UploadImage(c.UserContext(), imagePath, imageMimeType, file); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to upload image", err)
 	}
 
 	// Create ImageInfo struct
@@ -90,16 +97,18 @@ func (h *ImageRouteHandler) UploadImage(c *fiber.Ctx) error {
 	if user != nil {
 		userModel, ok := user.(*model.User)
 		if !ok {
-			return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
 		}
 		imageInfo.UserID = userModel.ID
 	}
 
 	// Save image info to db
-	err = h.imageDataStore.Create(imageInfo)
+	err = h.imageDataStore.Create(c.UserContext(), imageInfo)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to save image info to db", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save image info to db", err)
 	}
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newImageAuditEvent(c, model.AuditImageUpload, imageInfo))
+	h.webhookDispatcher.Dispatch(imageInfo.UserID, webhook.NewImageEvent(webhook.EventImageCreated, imageInfo))
 
 	// Return the image info
 	return c.Status(fiber.StatusCreated).JSON(imageInfo)
@@ -119,12 +128,12 @@ func (h *ImageRouteHandler) GetOwnImages(c *fiber.Ctx) error {
 	user := c.UserContext().Value("user")
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
 	}
 
-	images, err := h.imageDataStore.FindByUserID(userModel.ID)
+	images, err := h.imageDataStore.FindByUserID(c.UserContext(), userModel.ID)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "Unable to find images by user ID", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to find images by user ID", err)
 	}
 
 	return c.JSON(images)
@@ -133,29 +142,29 @@ func (h *ImageRouteHandler) GetOwnImages(c *fiber.Ctx) error {
 func (h *ImageRouteHandler) IsOwnImage(c *fiber.Ctx) error {
 	imageId := c.Params("imageID", "")
 	if len(imageId) == 0 {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "Image ID must be provided", nil)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Image ID must be provided", nil)
 	}
 	imageIDInt, err := strconv.Atoi(imageId)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusBadRequest, "Image ID must be integer", nil)
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Image ID must be integer", nil)
 	}
 
 	// Get userId
 	user := c.UserContext().Value("user")
 	userModel, ok := user.(*model.User)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
 	}
 
-	image, err := h.imageDataStore.FindByID(uint(imageIDInt))
+	image, err := h.imageDataStore.FindByID(c.UserContext(), uint(imageIDInt))
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "Unable to query image", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to query image", err)
 	}
 	if image == nil {
-		return NewHTTPError(h.log, fiber.StatusNotFound, "Image not found", nil)
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "Image not found", nil)
 	}
 	if image.UserID != userModel.ID {
-		return NewHTTPError(h.log, fiber.StatusForbidden, "Forbidden", nil)
+		return NewHTTPError(c, h.log, fiber.StatusForbidden, "Forbidden", nil)
 	}
 
 	c.SetUserContext(context.WithValue(c.UserContext(), "image", image))
@@ -177,20 +186,21 @@ func (h *ImageRouteHandler) IsOwnImage(c *fiber.Ctx) error {
 func (h *ImageRouteHandler) DeleteImage(c *fiber.Ctx) error {
 	image, ok := c.UserContext().Value("image").(*model.Image)
 	if !ok {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to parse image model", fmt.Errorf("unable to parse image model"))
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse image model", fmt.Errorf("unable to parse image model"))
 	}
 
 	// Delete image record in db
-	err := h.imageDataStore.DeleteByID(image.ID)
+	err := h.imageDataStore.DeleteByID(c.UserContext(), image.ID)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to delete image in db", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to delete image in db", err)
 	}
 
 	// Delete image on storage
-	err = h.imageStoreManager.DeleteImage(image.FilePath)
+	err = h.imageStoreManager.DeleteImage(c.UserContext(), image.FilePath)
 	if err != nil {
-		return NewHTTPError(h.log, fiber.StatusInternalServerError, "unable to delete image on storage", err)
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to delete image on storage", err)
 	}
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newImageAuditEvent(c, model.AuditImageDelete, image))
 
 	return c.JSON(image)
 }
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// inlineContentTypes are the content types that are safe to be displayed in the browser.
+// HTML, SVG and other types that can run scripts are always downloaded.
+var inlineContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/avif":      true,
+	"image/bmp":       true,
+	"application/pdf": true,
+	"video/mp4":       true,
+	"video/webm":      true,
+	"video/ogg":       true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+	"audio/wav":       true,
+	"audio/webm":      true,
+	"text/plain":      true,
+}
+
+const (
+	inlineContentSecurityPolicy = "default-src 'none'; img-src 'self'; media-src 'self'; style-src 'unsafe-inline'; sandbox"
+	// Chrome does not load its PDF viewer in a sandboxed document
+	inlinePDFContentSecurityPolicy = "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; object-src 'self'"
+)
+
+// inlineContentType returns the content type to serve the file inline with, and false if the file must be downloaded
+func inlineContentType(fileType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(fileType)
+	if err != nil || !inlineContentTypes[mediaType] {
+		return "", false
+	}
+	if mediaType == "text/plain" {
+		return fiber.MIMETextPlainCharsetUTF8, true
+	}
+	return mediaType, true
+}
+
+// wantInline reports whether the request asks for the inline mode with ?inline or ?inline=true
+func wantInline(c *fiber.Ctx) bool {
+	if !c.Context().QueryArgs().Has("inline") {
+		return false
+	}
+	value := c.Query("inline")
+	if len(value) == 0 {
+		return true
+	}
+	inline, err := strconv.ParseBool(value)
+	return err == nil && inline
+}
+
+// setInlineHeaders sets the headers to display the file in the browser
+func setInlineHeaders(c *fiber.Ctx, contentType string, filename string) {
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename}))
+	if strings.HasPrefix(contentType, "application/pdf") {
+		c.Set("Content-Security-Policy", inlinePDFContentSecurityPolicy)
+	} else {
+		c.Set("Content-Security-Policy", inlineContentSecurityPolicy)
+	}
+}
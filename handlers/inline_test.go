@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInlineContentType(t *testing.T) {
+	testCases := map[string]string{
+		"image/png":                 "image/png",
+		"image/jpeg":                "image/jpeg",
+		"application/pdf":           "application/pdf",
+		"video/mp4":                 "video/mp4",
+		"text/plain":                "text/plain; charset=utf-8",
+		"text/plain; charset=utf-8": "text/plain; charset=utf-8",
+		"IMAGE/GIF":                 "image/gif",
+	}
+	for fileType, expected := range testCases {
+		contentType, ok := inlineContentType(fileType)
+		require.True(t, ok, fileType)
+		require.Equal(t, expected, contentType, fileType)
+	}
+}
+
+func TestInlineContentTypeNotSafe(t *testing.T) {
+	for _, fileType := range []string{"text/html", "image/svg+xml", "application/xhtml+xml", "application/javascript", "application/octet-stream", "", "invalid"} {
+		_, ok := inlineContentType(fileType)
+		require.False(t, ok, fileType)
+	}
+}
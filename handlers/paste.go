@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"html/template"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var pasteFormatter = html.New(
+	html.WithClasses(true),
+	html.WithLineNumbers(true),
+	html.LineNumbersInTable(true),
+	html.LinkableLineNumbers(true, "L"),
+	html.TabWidth(4),
+)
+
+// UploadPaste handlers
+// @Summary Create new paste
+// @Description Store raw text as a file that can be viewed with syntax highlighting
+// @Tags Paste
+// @Accept  plain
+// @Produce  json
+// @Param       content  body  string  true  "Paste content"
+// @Param       title  query  string  false  "Paste title, used as the file name"
+// @Param       language  query  string  false  "Language for syntax highlighting, detected from the content if not provided"
+// @Param       slug  query  string  false  "Custom paste token"
+// @Param       duration  query  string  false  "Store duration in day (7), Go duration (1h30m) or ISO-8601 duration (PT1H30M)"
+// @Param       expired_at  query  string  false  "Expiry time in RFC3339"
+// @Success      201  {object}  model.File
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      413  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/paste [post]
+func (h *FileRoutesHandler) UploadPaste(c *fiber.Ctx) error {
+	content := c.Body()
+	if len(content) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Paste content must be provided", nil)
+	}
+	if int64(len(content)) > h.limits.MaxPasteSize {
+		return NewHTTPError(c, h.log, fiber.StatusRequestEntityTooLarge, "Paste too large", nil)
+	}
+	if !utf8.Valid(content) {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Paste content must be UTF-8 text", nil)
+	}
+
+	language := strings.ToLower(c.Query("language"))
+	if len(language) > 0 && lexers.Get(language) == nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("%s language is not supported", language), nil)
+	}
+	title := c.Query("title", "paste.txt")
+
+	fileInfo, err := h.newFileInfo(c, title, uint64(len(content)), fiber.MIMETextPlainCharsetUTF8)
+	if err != nil {
+		return err
+	}
+	fileInfo.IsPaste = true
+	fileInfo.Language = language
+
+	if err := h.writeFile(c.UserContext(), fileInfo, bytes.NewReader(content)); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to store the file", err)
+	}
+
+	err = h.fileDataStore.Create(c.UserContext(), fileInfo)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save file info to db", err)
+	}
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileUpload, fileInfo))
+	h.webhookDispatcher.Dispatch(fileInfo.UserID, webhook.NewFileEvent(webhook.EventFileCreated, fileInfo))
+
+	return c.Status(fiber.StatusCreated).JSON(fileInfo)
+}
+
+// GetPastePage handlers
+// @Summary View the paste
+// @Description Page showing the paste with syntax highlighting and line anchors
+// @Tags Paste
+// @Produce  html
+// @Param        token       path      string      true  "Paste Token"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /p/{token} [get]
+func (h *FileRoutesHandler) GetPastePage(c *fiber.Ctx) error {
+	fileInfo, content, err := h.readPaste(c)
+	if err != nil || fileInfo == nil {
+		return err
+	}
+
+	lexer := lexers.Get(fileInfo.Language)
+	if lexer == nil {
+		lexer = lexers.Match(fileInfo.Filename)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to tokenise paste", err)
+	}
+	style := styles.Get("github")
+	var code, css bytes.Buffer
+	if err := pasteFormatter.Format(&code, style, iterator); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to highlight paste", err)
+	}
+	if err := pasteFormatter.WriteCSS(&css, style); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to write highlight style", err)
+	}
+
+	var page bytes.Buffer
+	err = pastePageTemplate.ExecuteTemplate(&page, "layout", pastePage{
+		File:     fileInfo,
+		Language: lexer.Config().Name,
+		Code:     template.HTML(code.String()),
+		Style:    template.CSS(css.String()),
+	})
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to render paste page", err)
+	}
+	c.Set("Content-Type", fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(page.Bytes())
+}
+
+// GetPasteRaw handlers
+// @Summary Raw paste
+// @Description Get the paste content as plain text
+// @Tags Paste
+// @Produce  plain
+// @Param        token       path      string      true  "Paste Token"
+// @Success      200
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /p/{token}/raw [get]
+func (h *FileRoutesHandler) GetPasteRaw(c *fiber.Ctx) error {
+	fileInfo, content, err := h.readPaste(c)
+	if err != nil || fileInfo == nil {
+		return err
+	}
+
+	// Only the raw content is recorded as the download, like the file page the paste page is not counted
+	h.recordDownload(c.UserContext(), fileInfo, newDownloadSource(c), uint64(len(content)))
+	h.sendDownloadEvent(newDownloadEvent(c, fileInfo))
+	recordAudit(c.UserContext(), h.log, h.auditDataStore, newFileAuditEvent(c, model.AuditFileDownload, fileInfo))
+
+	c.Set("Content-Type", fiber.MIMETextPlainCharsetUTF8)
+	c.Set("Content-Disposition", "inline")
+	c.Set("X-Content-Type-Options", "nosniff")
+	user, _ := c.UserContext().Value("user").(*model.User)
+	body := h.downloadThrottle.Reader(c.UserContext(), io.NopCloser(strings.NewReader(content)), user)
+	return c.SendStream(body, len(content))
+}
+
+// readPaste finds the paste by token and reads its content, the download is not recorded.
+// It returns nil file if the response is already sent.
+func (h *FileRoutesHandler) readPaste(c *fiber.Ctx) (*model.File, string, error) {
+	fileInfo, err := h.fileDataStore.FindByToken(c.UserContext(), strings.ToLower(c.Params("token")))
+	if err != nil {
+		return nil, "", NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to get file query", err)
+	}
+	if fileInfo == nil {
+		return nil, "", c.Redirect(c.BaseURL() + "/404")
+	}
+	if !fileInfo.IsPaste {
+		return nil, "", c.Redirect(fmt.Sprintf("%s/%s", c.BaseURL(), fileInfo.Token))
+	}
+
+	// Check if file still exist on storage
+	if exist, err := h.storageManager.Exist(c.UserContext(), fileInfo.StorageKey()); !exist {
+		if err == nil {
+			return nil, "", c.Redirect(c.BaseURL() + "/404")
+		}
+		return nil, "", NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to check if file exist", err)
+	}
+
+	file, err := h.openFile(c.UserContext(), fileInfo)
+	if err != nil {
+		return nil, "", NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to open the file", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, h.limits.MaxPasteSize))
+	if err != nil {
+		return nil, "", NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to read paste", err)
+	}
+
+	return fileInfo, string(content), nil
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/ratelimit"
+)
+
+// RateLimitKey identifies the client for the rate limits, the user is only known after ParseUser.
+// The users are limited separately for their API key, so the scripts cannot use up the limits of the browser.
+// The anonymous clients and the requests before ParseUser are limited by IP.
+func RateLimitKey(c *fiber.Ctx) string {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return ratelimit.IPKey(c)
+	}
+	// ParseUser only uses the API key when there is no token cookie
+	if len(c.Cookies("token")) == 0 && len(c.Get("x-api-key")) > 0 {
+		return fmt.Sprintf("apikey:%d", userModel.ID)
+	}
+	return fmt.Sprintf("user:%d", userModel.ID)
+}
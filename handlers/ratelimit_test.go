@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/router"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitKey(t *testing.T) {
+	app := router.NewFiberRouter(fiber.DefaultBodyLimit)
+	app.Get("/", func(c *fiber.Ctx) error {
+		if c.Query("user") == "true" {
+			c.SetUserContext(context.WithValue(c.UserContext(), "user", &model.User{ID: 7}))
+		}
+		return c.SendString(RateLimitKey(c))
+	})
+
+	for _, tc := range []struct {
+		name   string
+		user   bool
+		cookie string
+		apiKey string
+		key    string
+	}{
+		{name: "anonymous", key: "ip:0.0.0.0"},
+		{name: "invalid api key", apiKey: "invalid", key: "ip:0.0.0.0"},
+		{name: "cookie", user: true, cookie: "jwt", key: "user:7"},
+		{name: "cookie and api key", user: true, cookie: "jwt", apiKey: "key", key: "user:7"},
+		{name: "api key", user: true, apiKey: "key", key: "apikey:7"},
+	} {
+		target := "/"
+		if tc.user {
+			target += "?user=true"
+		}
+		req := httptest.NewRequest(fiber.MethodGet, target, nil)
+		if len(tc.cookie) > 0 {
+			req.Header.Set(fiber.HeaderCookie, "token="+tc.cookie)
+		}
+		if len(tc.apiKey) > 0 {
+			req.Header.Set("x-api-key", tc.apiKey)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err, tc.name)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.key, string(body), tc.name)
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/fetch"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	RemoteUploadFetching = "fetching"
+	RemoteUploadDone     = "done"
+	RemoteUploadFailed   = "failed"
+)
+
+// remoteUploadRetention is how long the finished remote upload is kept for polling
+const remoteUploadRetention = time.Hour
+
+type RemoteUploadRequest struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+}
+
+type RemoteUpload struct {
+	ID           string      `json:"id"`
+	URL          string      `json:"url"`
+	Status       string      `json:"status"`
+	FetchedBytes int64       `json:"fetched_bytes"`
+	TotalBytes   int64       `json:"total_bytes"`
+	File         *model.File `json:"file,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+	userID       uint
+	// token is reserved until the file is saved, so other uploads can not take it meanwhile
+	token string
+}
+
+// remoteUploads keeps the progress of remote uploads in memory
+type remoteUploads struct {
+	mu      sync.Mutex
+	uploads map[string]*RemoteUpload
+}
+
+// add adds the upload, it returns false if the token is reserved by another fetching upload
+func (r *remoteUploads) add(upload *RemoteUpload) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.uploads == nil {
+		r.uploads = make(map[string]*RemoteUpload)
+	}
+	// Forget finished uploads that nobody polled for a while
+	for id, u := range r.uploads {
+		if u.Status != RemoteUploadFetching && time.Since(u.UpdatedAt) > remoteUploadRetention {
+			delete(r.uploads, id)
+		}
+	}
+	if r.reserved(upload.token) {
+		return false
+	}
+	r.uploads[upload.ID] = upload
+	return true
+}
+
+// tokenReserved checks if the token is reserved by the fetching upload
+func (r *remoteUploads) tokenReserved(token string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reserved(token)
+}
+
+func (r *remoteUploads) reserved(token string) bool {
+	for _, u := range r.uploads {
+		if u.Status == RemoteUploadFetching && u.token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the copy of the upload, so it can be read while the fetch is running
+func (r *remoteUploads) get(id string) (RemoteUpload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	upload, ok := r.uploads[id]
+	if !ok {
+		return RemoteUpload{}, false
+	}
+	return *upload, true
+}
+
+func (r *remoteUploads) update(id string, fn func(upload *RemoteUpload)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if upload, ok := r.uploads[id]; ok {
+		fn(upload)
+		upload.UpdatedAt = time.Now().UTC()
+	}
+}
+
+// UploadRemoteFile handlers
+// @Summary Upload new file from URL
+// @Description Fetch the file from the URL on the server and store it like an uploaded file. The progress can be polled from the returned ID.
+// @Tags File
+// @Accept  json
+// @Produce  json
+// @Param       request  body  handlers.RemoteUploadRequest  true  "Remote file"
+// @Param       slug  query  string  false  "Custom file token"
+// @Param       duration  query  string  false  "Store duration in day (7), Go duration (1h30m) or ISO-8601 duration (PT1H30M)"
+// @Param       expired_at  query  string  false  "Expiry time in RFC3339"
+// @Success      202  {object}  handlers.RemoteUpload
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/file/remote [post]
+func (h *FileRoutesHandler) UploadRemoteFile(c *fiber.Ctx) error {
+	var request RemoteUploadRequest
+	if err := c.BodyParser(&request); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Invalid request body", nil)
+	}
+	remoteURL, err := url.Parse(request.URL)
+	if err != nil || (remoteURL.Scheme != "http" && remoteURL.Scheme != "https") || len(remoteURL.Host) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "URL must be valid http or https URL", nil)
+	}
+
+	fileInfo, err := h.newFileInfo(c, request.Filename, 0, "")
+	if err != nil {
+		return err
+	}
+
+	upload := &RemoteUpload{
+		ID:         fileInfo.ID,
+		URL:        remoteURL.String(),
+		Status:     RemoteUploadFetching,
+		TotalBytes: -1,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		userID:     fileInfo.UserID,
+		token:      fileInfo.Token,
+	}
+	if !h.remoteUploads.add(upload) {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("%s slug is used", fileInfo.Token), nil)
+	}
+
+	// The fetch outlives the request, so it only keeps the trace of the request and is bounded by the fetch and storage timeouts
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(c.UserContext()))
+	go h.fetchRemoteFile(ctx, upload.ID, upload.URL, fileInfo, newFileAuditEvent(c, model.AuditFileUpload, fileInfo))
+
+	return c.Status(fiber.StatusAccepted).JSON(upload)
+}
+
+// GetRemoteUpload handlers
+// @Summary Get remote upload progress
+// @Description Get the progress of the file upload from URL
+// @Tags File
+// @Produce  json
+// @Param        uploadID       path      string      true  "Remote upload ID"
+// @Success      200  {object}  handlers.RemoteUpload
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Router /api/file/remote/{uploadID} [get]
+func (h *FileRoutesHandler) GetRemoteUpload(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	upload, ok := h.remoteUploads.get(c.Params("uploadID"))
+	if !ok || upload.userID != userModel.ID {
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "Remote upload not found", nil)
+	}
+	return c.JSON(upload)
+}
+
+// fetchRemoteFile fetches the remote file and stores it the same way as UploadFile.
+// The audit event is created before the request is reused and recorded when the file is saved.
+func (h *FileRoutesHandler) fetchRemoteFile(ctx context.Context, uploadID string, remoteURL string, fileInfo *model.File, auditEvent *model.AuditEvent) {
+	fail := func(message string, err error) {
+		h.log.Infow("unable to upload remote file", "url", remoteURL, "error", err)
+		h.remoteUploads.update(uploadID, func(upload *RemoteUpload) {
+			upload.Status = RemoteUploadFailed
+			upload.Error = message
+		})
+	}
+
+	remoteFile, err := h.fetchManager.Fetch(ctx, remoteURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, fetch.ErrForbiddenAddress):
+			fail("URL points to a forbidden address", err)
+		case errors.Is(err, fetch.ErrTooLarge):
+			fail("Remote file too large", err)
+		default:
+			fail(fmt.Sprintf("Unable to fetch the URL: %v", err), err)
+		}
+		return
+	}
+	defer remoteFile.Body.Close()
+
+	if len(fileInfo.Filename) == 0 {
+		fileInfo.Filename = remoteFile.Filename
+	}
+	fileInfo.FileType = remoteFile.ContentType
+	h.remoteUploads.update(uploadID, func(upload *RemoteUpload) {
+		upload.TotalBytes = remoteFile.Size
+	})
+
+	body := &progressReader{reader: remoteFile.Body, onProgress: func(fetched int64) {
+		h.remoteUploads.update(uploadID, func(upload *RemoteUpload) {
+			upload.FetchedBytes = fetched
+		})
+	}}
+	if err := h.writeFile(ctx, fileInfo, body); err != nil {
+		if errors.Is(body.err, fetch.ErrTooLarge) {
+			fail("Remote file too large", err)
+		} else {
+			fail("Unable to store the remote file", err)
+		}
+		h.deleteRemoteFile(ctx, fileInfo)
+		return
+	}
+	fileInfo.FileSize = uint64(body.fetched)
+	if err := h.deduplicate(ctx, fileInfo); err != nil {
+		fail("Unable to store the remote file", err)
+		h.deleteRemoteFile(ctx, fileInfo)
+		return
+	}
+
+	// The slug could be taken by another server while the file was fetched
+	existingFile, err := h.fileDataStore.FindByToken(ctx, fileInfo.Token)
+	if err != nil || existingFile != nil {
+		if err != nil {
+			fail("Unable to save the file", err)
+		} else {
+			fail(fmt.Sprintf("%s slug is used", fileInfo.Token), nil)
+		}
+		h.deleteRemoteFile(ctx, fileInfo)
+		return
+	}
+	if err := h.fileDataStore.Create(ctx, fileInfo); err != nil {
+		h.log.Errorw("unable to save file info to db", "error", err)
+		fail("Unable to save the file", err)
+		h.deleteRemoteFile(ctx, fileInfo)
+		return
+	}
+	recordAudit(ctx, h.log, h.auditDataStore, auditEvent)
+	h.webhookDispatcher.Dispatch(fileInfo.UserID, webhook.NewFileEvent(webhook.EventFileCreated, fileInfo))
+
+	h.remoteUploads.update(uploadID, func(upload *RemoteUpload) {
+		upload.Status = RemoteUploadDone
+		upload.FetchedBytes = int64(fileInfo.FileSize)
+		upload.File = fileInfo
+	})
+}
+
+func (h *FileRoutesHandler) deleteRemoteFile(ctx context.Context, fileInfo *model.File) {
+	if exist, _ := h.storageManager.Exist(ctx, fileInfo.StorageKey()); exist {
+		_ = h.deleteFileContent(ctx, fileInfo)
+	}
+}
+
+// progressReader reports the number of bytes read at most every second
+type progressReader struct {
+	reader       io.Reader
+	fetched      int64
+	err          error
+	lastReported time.Time
+	onProgress   func(fetched int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.fetched += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	if time.Since(r.lastReported) > time.Second || err == io.EOF {
+		r.lastReported = time.Now()
+		r.onProgress(r.fetched)
+	}
+	return n, err
+}
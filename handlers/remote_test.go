@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRemoteUploadTokenReserved(t *testing.T) {
+	var uploads remoteUploads
+	require.True(t, uploads.add(&RemoteUpload{ID: "1", Status: RemoteUploadFetching, token: "slug"}))
+	require.True(t, uploads.tokenReserved("slug"))
+	require.False(t, uploads.add(&RemoteUpload{ID: "2", Status: RemoteUploadFetching, token: "slug"}))
+
+	// The token is not reserved after the fetch is finished
+	uploads.update("1", func(upload *RemoteUpload) {
+		upload.Status = RemoteUploadFailed
+	})
+	require.False(t, uploads.tokenReserved("slug"))
+	require.True(t, uploads.add(&RemoteUpload{ID: "2", Status: RemoteUploadFetching, token: "slug"}))
+}
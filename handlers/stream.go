@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"io"
+)
+
+// streamReadCloser cancels the context of the response stream after the stream is closed.
+// fasthttp closes the stream when it is sent or when writing it fails because the client disconnected,
+// so the storage reads and throttle waits of the stream stop with the client.
+type streamReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *streamReadCloser) Close() error {
+	// The download is recorded when the stream is closed, so the context is cancelled after it
+	err := r.ReadCloser.Close()
+	r.cancel()
+	return err
+}
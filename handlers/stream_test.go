@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+type contextCloser struct {
+	io.Reader
+	ctx       context.Context
+	closeErr  error
+	ctxClosed bool
+}
+
+func (c *contextCloser) Close() error {
+	c.ctxClosed = c.ctx.Err() != nil
+	return c.closeErr
+}
+
+func TestStreamReadCloser(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inner := &contextCloser{Reader: strings.NewReader("content"), ctx: ctx, closeErr: io.ErrClosedPipe}
+	stream := &streamReadCloser{ReadCloser: inner, cancel: cancel}
+
+	require.ErrorIs(t, stream.Close(), io.ErrClosedPipe)
+	// The inner reader is closed with the live context, then the context is cancelled
+	require.False(t, inner.ctxClosed)
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"html/template"
+	"time"
+)
+
+var templateFuncs = template.FuncMap{
+	"formatSize": formatSize,
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("2 Jan 2006 15:04 MST")
+	},
+	"isPermanent": isPermanent,
+}
+
+const layoutTemplate = `{{define "layout"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{template "title" .}} - CSCMS Storage</title>
+<link rel="icon" href="/favicon.ico">
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #f5f6fa; color: #2f3640; margin: 0; }
+main { max-width: 720px; margin: 48px auto; padding: 32px; background: #fff; border-radius: 12px; box-shadow: 0 2px 12px rgba(0, 0, 0, .08); }
+h1 { font-size: 1.4rem; word-break: break-all; }
+table { width: 100%; border-collapse: collapse; margin: 16px 0; }
+td { padding: 8px 4px; border-bottom: 1px solid #eee; word-break: break-all; }
+td.size { text-align: right; white-space: nowrap; color: #718093; }
+.meta { color: #718093; font-size: .9rem; }
+.button { display: inline-block; padding: 10px 20px; border-radius: 8px; background: #0097e6; color: #fff; text-decoration: none; }
+</style>
+{{block "head" .}}{{end}}
+</head>
+<body>
+<main>
+{{template "content" .}}
+</main>
+</body>
+</html>{{end}}`
+
+const bundleTemplate = `{{define "title"}}Shared files{{end}}
+{{define "content"}}
+<h1>{{len .Files}} shared files</h1>
+<p class="meta">{{if isPermanent .ExpiredAt}}Never expires{{else}}Available until {{formatTime .ExpiredAt}}{{end}}</p>
+<table>
+{{range .Files}}<tr>
+<td><a href="/b/{{$.Token}}/{{.ID}}">{{.Filename}}</a></td>
+<td class="size">{{formatSize .FileSize}}</td>
+</tr>
+{{end}}</table>
+<a class="button" href="/b/{{.Token}}/zip">Download all (zip)</a>
+{{end}}`
+
+const pasteTemplate = `{{define "title"}}{{.File.Filename}}{{end}}
+{{define "head"}}<style>
+main { max-width: 960px; }
+.code { overflow-x: auto; margin: 16px 0; font-size: .85rem; }
+.code pre { margin: 0; }
+.code span:target { background: #fff3bf; }
+{{.Style}}
+</style>{{end}}
+{{define "content"}}
+<h1>{{.File.Filename}}</h1>
+<p class="meta">{{.Language}} &middot; {{formatSize .File.FileSize}} &middot; {{if isPermanent .File.ExpiredAt}}never expires{{else}}available until {{formatTime .File.ExpiredAt}}{{end}}</p>
+<a href="/p/{{.File.Token}}/raw">Raw</a> &middot; <a href="/{{.File.Token}}/download">Download</a>
+<div class="code">{{.Code}}</div>
+{{end}}`
+
+const fileTemplate = `{{define "title"}}{{.File.Filename}}{{end}}
+{{define "head"}}<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="CSCMS Storage">
+<meta property="og:title" content="{{.File.Filename}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{if .ImageURL}}<meta property="og:image" content="{{.ImageURL}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="{{.ImageURL}}">
+{{else}}<meta name="twitter:card" content="summary">
+{{end}}<meta name="twitter:title" content="{{.File.Filename}}">
+<meta name="twitter:description" content="{{.Description}}">{{end}}
+{{define "content"}}
+<h1>{{.File.Filename}}</h1>
+<table>
+<tr><td>Size</td><td class="size">{{formatSize .File.FileSize}}</td></tr>
+<tr><td>Uploaded by</td><td class="size">{{.Uploader}}</td></tr>
+{{if isPermanent .File.ExpiredAt}}<tr><td>Expiry</td><td class="size">Never expires</td></tr>
+{{else}}<tr><td>Available until</td><td class="size">{{formatTime .File.ExpiredAt}}</td></tr>
+{{end}}
+</table>
+{{if .File.ClientEncrypted}}<p class="meta">This file is end-to-end encrypted. The key is only in the share link, so it must be decrypted by the CSCMS Storage client.</p>
+{{end}}<a class="button" href="/{{.File.Token}}/download">Download</a>
+{{if .Previewable}}<a href="/{{.File.Token}}/download?inline">Preview</a>{{end}}
+{{end}}`
+
+type filePage struct {
+	File        *model.File
+	Uploader    string
+	Description string
+	URL         string
+	ImageURL    string
+	Previewable bool
+}
+
+type pastePage struct {
+	File     *model.File
+	Language string
+	Code     template.HTML
+	Style    template.CSS
+}
+
+var (
+	filePageTemplate   = newPageTemplate("file", fileTemplate)
+	bundlePageTemplate = newPageTemplate("bundle", bundleTemplate)
+	pastePageTemplate  = newPageTemplate("paste", pasteTemplate)
+)
+
+func newPageTemplate(name string, content string) *template.Template {
+	return template.Must(template.Must(template.New(name).Funcs(templateFuncs).Parse(layoutTemplate)).Parse(content))
+}
+
+// isPermanent reports whether the expiry is the one of the content kept forever
+func isPermanent(expiredAt time.Time) bool {
+	return !expiredAt.Before(model.PermanentExpiry)
+}
+
+// formatSize formats the size in bytes to human-readable size
+func formatSize(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/token"
+	"github.com/thetkpark/cscms-temp-storage/service/webhook"
+	"go.uber.org/zap"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxWebhookDeliveries = 100
+
+type WebhookRouteHandler struct {
+	log               *zap.SugaredLogger
+	webhookDataStore  data.WebhookDataStore
+	tokenManager      token.Manager
+	webhookDispatcher webhook.Dispatcher
+}
+
+func NewWebhookRouteHandler(log *zap.SugaredLogger, webhookDataStore data.WebhookDataStore, token token.Manager, dispatcher webhook.Dispatcher) *WebhookRouteHandler {
+	return &WebhookRouteHandler{
+		log:               log,
+		webhookDataStore:  webhookDataStore,
+		tokenManager:      token,
+		webhookDispatcher: dispatcher,
+	}
+}
+
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Secret signs the payloads, it is generated if not provided
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// CreatedWebhook is the webhook with its secret, the secret is only returned once
+type CreatedWebhook struct {
+	model.Webhook
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook handlers
+// @Summary Create webhook
+// @Description Subscribe the URL to the events of the files and images of the user. The payload is signed with HMAC-SHA256 in the X-Webhook-Signature header as t=<unix time>,v1=<hex HMAC of "<unix time>.<payload>">
+// @Tags Webhook
+// @Accept  json
+// @Produce  json
+// @Param       request  body  handlers.WebhookRequest  true  "Webhook, events are file.created, file.downloaded, file.expired, file.deleted and image.created"
+// @Success      201  {object}  handlers.CreatedWebhook
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/webhook [post]
+func (h *WebhookRouteHandler) CreateWebhook(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	var request WebhookRequest
+	if err := c.BodyParser(&request); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Invalid request body", nil)
+	}
+	webhookURL, err := url.Parse(request.URL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || len(webhookURL.Host) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "URL must be valid http or https URL", nil)
+	}
+	if len(request.Events) == 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "At least one event must be provided", nil)
+	}
+	for _, event := range request.Events {
+		if !webhook.ValidEventType(event) {
+			return NewHTTPError(c, h.log, fiber.StatusBadRequest, fmt.Sprintf("%s event is not supported", event), nil)
+		}
+	}
+	secret := request.Secret
+	if len(secret) == 0 {
+		if secret, err = h.tokenManager.GenerateWebhookSecret(); err != nil {
+			return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to generate webhook secret", err)
+		}
+	}
+
+	webhookModel := &model.Webhook{
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userModel.ID,
+		URL:       webhookURL.String(),
+		Secret:    secret,
+		Events:    strings.Join(request.Events, ","),
+	}
+	if err := h.webhookDataStore.Create(c.UserContext(), webhookModel); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to save webhook", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(CreatedWebhook{Webhook: *webhookModel, Secret: secret})
+}
+
+// GetOwnWebhooks handlers
+// @Summary List webhooks
+// @Description List the webhooks of the user
+// @Tags Webhook
+// @Produce  json
+// @Success      200  {array}  model.Webhook
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/webhook [get]
+func (h *WebhookRouteHandler) GetOwnWebhooks(c *fiber.Ctx) error {
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	webhooks, err := h.webhookDataStore.FindByUserID(c.UserContext(), userModel.ID)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to find webhooks by user ID", err)
+	}
+	if webhooks == nil {
+		webhooks = []model.Webhook{}
+	}
+	return c.JSON(webhooks)
+}
+
+func (h *WebhookRouteHandler) IsOwnWebhook(c *fiber.Ctx) error {
+	webhookID, err := strconv.ParseUint(c.Params("webhookID"), 10, 64)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "Webhook ID must be integer", nil)
+	}
+
+	userModel, ok := c.UserContext().Value("user").(*model.User)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse to user model", fmt.Errorf("user model convertion error"))
+	}
+
+	webhookModel, err := h.webhookDataStore.FindByID(c.UserContext(), uint(webhookID))
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "Unable to query webhook", err)
+	}
+	if webhookModel == nil {
+		return NewHTTPError(c, h.log, fiber.StatusNotFound, "Webhook not found", nil)
+	}
+	if webhookModel.UserID != userModel.ID {
+		return NewHTTPError(c, h.log, fiber.StatusForbidden, "Forbidden", nil)
+	}
+
+	c.SetUserContext(context.WithValue(c.UserContext(), "webhook", webhookModel))
+	return c.Next()
+}
+
+// DeleteWebhook handlers
+// @Summary Delete webhook
+// @Description Delete the webhook with its delivery log
+// @Tags Webhook
+// @Produce  json
+// @Param        webhookID       path      int      true  "Webhook ID"
+// @Success      200  {object}  model.Webhook
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/webhook/{webhookID} [delete]
+func (h *WebhookRouteHandler) DeleteWebhook(c *fiber.Ctx) error {
+	webhookModel, ok := c.UserContext().Value("webhook").(*model.Webhook)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse webhook model", fmt.Errorf("unable to parse webhook model"))
+	}
+
+	if err := h.webhookDataStore.DeleteByID(c.UserContext(), webhookModel.ID); err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to delete webhook", err)
+	}
+	return c.JSON(webhookModel)
+}
+
+// GetWebhookDeliveries handlers
+// @Summary List webhook deliveries
+// @Description List the latest delivery attempts of the webhook, from the newest
+// @Tags Webhook
+// @Produce  json
+// @Param        webhookID       path      int      true  "Webhook ID"
+// @Param        limit       query      int      false  "Maximum number of deliveries (at most 100)"
+// @Success      200  {array}  model.WebhookDelivery
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/webhook/{webhookID}/deliveries [get]
+func (h *WebhookRouteHandler) GetWebhookDeliveries(c *fiber.Ctx) error {
+	webhookModel, ok := c.UserContext().Value("webhook").(*model.Webhook)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse webhook model", fmt.Errorf("unable to parse webhook model"))
+	}
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(maxWebhookDeliveries)))
+	if err != nil || limit <= 0 {
+		return NewHTTPError(c, h.log, fiber.StatusBadRequest, "limit must be positive integer", nil)
+	}
+	if limit > maxWebhookDeliveries {
+		limit = maxWebhookDeliveries
+	}
+
+	deliveries, err := h.webhookDataStore.FindDeliveries(c.UserContext(), webhookModel.ID, limit)
+	if err != nil {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to query webhook deliveries", err)
+	}
+	if deliveries == nil {
+		deliveries = []model.WebhookDelivery{}
+	}
+	return c.JSON(deliveries)
+}
+
+// TestWebhook handlers
+// @Summary Test webhook
+// @Description Send the webhook.test event to the webhook once and return the delivery
+// @Tags Webhook
+// @Produce  json
+// @Param        webhookID       path      int      true  "Webhook ID"
+// @Success      200  {object}  model.WebhookDelivery
+// @Failure      400  {object}  handlers.ErrorResponse
+// @Failure      401  {object}  handlers.ErrorResponse
+// @Failure      403  {object}  handlers.ErrorResponse
+// @Failure      404  {object}  handlers.ErrorResponse
+// @Failure      500  {object}  handlers.ErrorResponse
+// @Router /api/webhook/{webhookID}/test [post]
+func (h *WebhookRouteHandler) TestWebhook(c *fiber.Ctx) error {
+	webhookModel, ok := c.UserContext().Value("webhook").(*model.Webhook)
+	if !ok {
+		return NewHTTPError(c, h.log, fiber.StatusInternalServerError, "unable to parse webhook model", fmt.Errorf("unable to parse webhook model"))
+	}
+
+	delivery := h.webhookDispatcher.Deliver(c.UserContext(), webhookModel, webhook.NewEvent(webhook.EventTest, fiber.Map{
+		"webhook_id": webhookModel.ID,
+	}))
+	return c.JSON(delivery)
+}
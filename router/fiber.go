@@ -1,10 +1,14 @@
 package router
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
 
-func NewFiberRouter() *fiber.App {
+// NewFiberRouter creates the app that rejects the request body larger than the body limit in bytes
+func NewFiberRouter(bodyLimit int) *fiber.App {
 	return fiber.New(fiber.Config{
-		BodyLimit: 150 << 20,
+		BodyLimit: bodyLimit,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			// Default to 500
 			code := fiber.StatusInternalServerError
@@ -19,9 +23,12 @@ func NewFiberRouter() *fiber.App {
 
 			c.Status(code)
 
+			// The request ID is returned to find the request in the access log
+			requestID, _ := c.Locals(requestid.ConfigDefault.ContextKey).(string)
 			return c.JSON(fiber.Map{
-				"code":    code,
-				"message": message,
+				"code":       code,
+				"message":    message,
+				"request_id": requestID,
 			})
 		},
 	})
@@ -0,0 +1,32 @@
+// Package e2e implements the format of the end-to-end encrypted files.
+//
+// Files uploaded with client_encrypted=true are encrypted by the client before the upload,
+// so the server only stores the ciphertext and never sees the key.
+// The server still encrypts the ciphertext at rest like any other file.
+//
+// # Format
+//
+// The content is encrypted with DARE 2.0 (https://github.com/minio/sio),
+// which is AES-256-GCM or ChaCha20-Poly1305 over 64KiB packages with authenticated sequence numbers.
+// Every encrypted file starts with the 16 bytes header of the first package, the first byte is the version 0x20.
+// The key is 32 random bytes generated by the client for each file and must not be reused.
+//
+// # Key in URL fragment
+//
+// The key is encoded with unpadded base64url and put in the URL fragment of the share link:
+//
+//	https://storage.cscms.me/<token>#<key>
+//
+// Browsers never send the fragment to the server, so the server cannot learn the key from the link either.
+//
+// # Download
+//
+// GET /<token>/download returns the ciphertext as application/octet-stream with the headers
+//
+//	X-Client-Encrypted: true
+//	X-Client-Encryption-Format: DARE-2.0
+//	X-Decrypted-Size: <size of the plaintext in bytes>
+//
+// The client decrypts the body with the key from the fragment.
+// The file name is stored as uploaded, so clients that want to hide it should upload a generic name.
+package e2e
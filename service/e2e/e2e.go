@@ -0,0 +1,82 @@
+package e2e
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/minio/sio"
+	"io"
+	"strings"
+)
+
+const (
+	// Format is the name of the encryption format in the X-Client-Encryption-Format header
+	Format = "DARE-2.0"
+	// KeySize is the size of the file key in bytes
+	KeySize = 32
+	// HeaderSize is the size of the DARE package header at the beginning of the file
+	HeaderSize = 16
+)
+
+// NewKey generates a random file key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncodeKey encodes the key for the URL fragment
+func EncodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// DecodeKey decodes the key from the URL fragment
+func DecodeKey(encodedKey string) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(encodedKey, "#"))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	return key, nil
+}
+
+// ShareURL returns the link of the file with the key in the fragment
+func ShareURL(fileURL string, key []byte) string {
+	return fmt.Sprintf("%s#%s", fileURL, EncodeKey(key))
+}
+
+func config(key []byte) sio.Config {
+	return sio.Config{
+		MinVersion: sio.Version20,
+		MaxVersion: sio.Version20,
+		Key:        key,
+	}
+}
+
+// Encrypt encrypts the content from src to dst with the key
+func Encrypt(dst io.Writer, src io.Reader, key []byte) (int64, error) {
+	return sio.Encrypt(dst, src, config(key))
+}
+
+// Decrypt decrypts the content from src to dst with the key
+func Decrypt(dst io.Writer, src io.Reader, key []byte) (int64, error) {
+	return sio.Decrypt(dst, src, config(key))
+}
+
+// ValidHeader reports whether the beginning of the file is the header of DARE 2.0
+func ValidHeader(header []byte) bool {
+	if len(header) < HeaderSize {
+		return false
+	}
+	// version and cipher suite (AES-256-GCM or ChaCha20-Poly1305)
+	return header[0] == sio.Version20 && (header[1] == sio.AES_256_GCM || header[1] == sio.CHACHA20_POLY1305)
+}
+
+// DecryptedSize returns the size of the plaintext from the size of the ciphertext
+func DecryptedSize(encryptedSize uint64) (uint64, error) {
+	return sio.DecryptedSize(encryptedSize)
+}
@@ -0,0 +1,55 @@
+package e2e
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	inputString := strings.Repeat("Hello World, This should be encrypted. ", 4096)
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	var encrypted bytes.Buffer
+	_, err = Encrypt(&encrypted, strings.NewReader(inputString), key)
+	require.NoError(t, err)
+	require.True(t, ValidHeader(encrypted.Bytes()))
+
+	size, err := DecryptedSize(uint64(encrypted.Len()))
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(inputString)), size)
+
+	var decrypted bytes.Buffer
+	_, err = Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes()), key)
+	require.NoError(t, err)
+	require.Equal(t, inputString, decrypted.String())
+
+	// Another key cannot decrypt the file
+	otherKey, err := NewKey()
+	require.NoError(t, err)
+	_, err = Decrypt(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), otherKey)
+	require.Error(t, err)
+}
+
+func TestValidHeader(t *testing.T) {
+	require.False(t, ValidHeader([]byte("plain text file that is long enough")))
+	require.False(t, ValidHeader([]byte{0x20, 0x00}))
+}
+
+func TestShareURL(t *testing.T) {
+	key, err := NewKey()
+	require.NoError(t, err)
+
+	shareURL := ShareURL("https://storage.cscms.me/abc", key)
+	parts := strings.SplitN(shareURL, "#", 2)
+	require.Equal(t, "https://storage.cscms.me/abc", parts[0])
+
+	decodedKey, err := DecodeKey(parts[1])
+	require.NoError(t, err)
+	require.Equal(t, key, decodedKey)
+
+	_, err = DecodeKey("#c2hvcnQ")
+	require.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"github.com/minio/sio"
+	"go.uber.org/zap"
+	"io"
+)
+
+// EnvelopeEncryptionManager encrypts every file with its own random data key.
+// The data key is wrapped by the KeyProvider and stored with the file,
+// so the file cannot be decrypted with the database and the disk alone.
+type EnvelopeEncryptionManager struct {
+	log      *zap.SugaredLogger
+	provider KeyProvider
+	legacy   Manager
+}
+
+// NewEnvelopeEncryptionManager creates the manager.
+// legacy is used to decrypt the files that were encrypted before envelope encryption, it can be nil.
+func NewEnvelopeEncryptionManager(l *zap.SugaredLogger, provider KeyProvider, legacy Manager) *EnvelopeEncryptionManager {
+	return &EnvelopeEncryptionManager{
+		log:      l,
+		provider: provider,
+		legacy:   legacy,
+	}
+}
+
+func (m *EnvelopeEncryptionManager) CurrentKeyID() string {
+	return m.provider.KeyID()
+}
+
+func (m *EnvelopeEncryptionManager) Encrypt(ctx context.Context, input io.Reader) (io.Reader, *Metadata, error) {
+	// generate the random data key for this file only
+	var dataKey [32]byte
+	if _, err := io.ReadFull(rand.Reader, dataKey[:]); err != nil {
+		m.log.Errorw("Failed to read random data", "error", err)
+		return nil, nil, err
+	}
+
+	wrappedKey, err := m.provider.WrapKey(ctx, dataKey[:])
+	if err != nil {
+		m.log.Errorw("Failed to wrap data key", "error", err)
+		return nil, nil, err
+	}
+
+	encrypted, err := sio.EncryptReader(input, sio.Config{Key: dataKey[:]})
+	if err != nil {
+		m.log.Errorw("Failed to encrypted reader", "error", err)
+		return nil, nil, err
+	}
+
+	return encrypted, &Metadata{KeyID: m.provider.KeyID(), WrappedKey: wrappedKey}, nil
+}
+
+func (m *EnvelopeEncryptionManager) Decrypt(ctx context.Context, input io.Reader, metadata *Metadata) (io.Reader, error) {
+	// Files without wrapped key are encrypted with the key derived from the master key
+	if len(metadata.WrappedKey) == 0 {
+		if m.legacy == nil {
+			err := fmt.Errorf("file is encrypted without data key and no legacy master key is loaded")
+			m.log.Errorw("Failed to decrypt legacy file", "error", err)
+			return nil, err
+		}
+		return m.legacy.Decrypt(ctx, input, metadata)
+	}
+
+	dataKey, err := m.provider.UnwrapKey(ctx, metadata.KeyID, metadata.WrappedKey)
+	if err != nil {
+		m.log.Errorw("Failed to unwrap data key", "error", err)
+		return nil, err
+	}
+
+	return sio.DecryptReader(input, sio.Config{Key: dataKey})
+}
+
+// Rewrap wraps the data key of the file with the current KEK without touching the file content
+func (m *EnvelopeEncryptionManager) Rewrap(ctx context.Context, metadata *Metadata) (*Metadata, error) {
+	dataKey, err := m.provider.UnwrapKey(ctx, metadata.KeyID, metadata.WrappedKey)
+	if err != nil {
+		m.log.Errorw("Failed to unwrap data key", "error", err)
+		return nil, err
+	}
+	wrappedKey, err := m.provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		m.log.Errorw("Failed to wrap data key", "error", err)
+		return nil, err
+	}
+	return &Metadata{KeyID: m.provider.KeyID(), WrappedKey: wrappedKey}, nil
+}
+
+// NewManagerFromConfig creates the envelope encryption manager with the key provider in the config.
+// The master keys in the config are also used to decrypt the files from before envelope encryption.
+func NewManagerFromConfig(l *zap.SugaredLogger, config KeyProviderConfig) (*EnvelopeEncryptionManager, error) {
+	provider, err := NewKeyProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var legacy Manager
+	if len(config.MasterKey) > 0 {
+		legacy, err = NewSIOEncryptionManager(l, config.MasterKeyID, config.MasterKey, config.OldMasterKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewEnvelopeEncryptionManager(l, provider, legacy), nil
+}
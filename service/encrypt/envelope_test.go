@@ -0,0 +1,82 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encryptEnvelope(t *testing.T, manager Manager, input string) ([]byte, *Metadata) {
+	encryptedReader, metadata, err := manager.Encrypt(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	encrypted, err := io.ReadAll(encryptedReader)
+	require.NoError(t, err)
+	return encrypted, metadata
+}
+
+func decryptEnvelope(t *testing.T, manager Manager, encrypted []byte, metadata *Metadata) string {
+	decryptedReader, err := manager.Decrypt(context.Background(), bytes.NewReader(encrypted), metadata)
+	require.NoError(t, err)
+	decrypted, err := io.ReadAll(decryptedReader)
+	require.NoError(t, err)
+	return string(decrypted)
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	inputString := "Hello World, This should be encrypted"
+	manager, err := NewManagerFromConfig(zap.NewNop().Sugar(), KeyProviderConfig{MasterKey: EncryptionKey})
+	require.NoError(t, err)
+
+	encrypted, metadata := encryptEnvelope(t, manager, inputString)
+	require.NotContains(t, string(encrypted), inputString)
+	require.Equal(t, DefaultKeyID, metadata.KeyID)
+	require.NotEmpty(t, metadata.WrappedKey)
+	require.Empty(t, metadata.Nonce)
+	require.Equal(t, inputString, decryptEnvelope(t, manager, encrypted, metadata))
+
+	// Every file has its own data key
+	_, otherMetadata := encryptEnvelope(t, manager, inputString)
+	require.NotEqual(t, metadata.WrappedKey, otherMetadata.WrappedKey)
+}
+
+func TestEnvelopeDecryptLegacy(t *testing.T) {
+	inputString := "Hello World, This should be encrypted"
+	sugarLogger := zap.NewNop().Sugar()
+	legacy, err := NewSIOEncryptionManager(sugarLogger, "", EncryptionKey, nil)
+	require.NoError(t, err)
+	encrypted, metadata := encryptString(t, legacy, inputString)
+
+	manager, err := NewManagerFromConfig(sugarLogger, KeyProviderConfig{MasterKey: EncryptionKey})
+	require.NoError(t, err)
+	require.Equal(t, inputString, decryptEnvelope(t, manager, encrypted, metadata))
+
+	// Legacy files cannot be decrypted without the master key
+	provider, err := NewStaticKeyProvider("", EncryptionKey, nil)
+	require.NoError(t, err)
+	_, err = NewEnvelopeEncryptionManager(sugarLogger, provider, nil).Decrypt(context.Background(), bytes.NewReader(encrypted), metadata)
+	require.Error(t, err)
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	inputString := "Hello World, This should be encrypted"
+	sugarLogger := zap.NewNop().Sugar()
+	oldManager, err := NewManagerFromConfig(sugarLogger, KeyProviderConfig{MasterKeyID: "2021", MasterKey: EncryptionKey})
+	require.NoError(t, err)
+	encrypted, metadata := encryptEnvelope(t, oldManager, inputString)
+
+	newManager, err := NewManagerFromConfig(sugarLogger, KeyProviderConfig{MasterKeyID: "2022", MasterKey: "new master key", OldMasterKeys: map[string]string{"2021": EncryptionKey}})
+	require.NoError(t, err)
+	newMetadata, err := newManager.Rewrap(context.Background(), metadata)
+	require.NoError(t, err)
+	require.Equal(t, "2022", newMetadata.KeyID)
+	require.Equal(t, inputString, decryptEnvelope(t, newManager, encrypted, newMetadata))
+
+	// The old key is no longer needed for the rewrapped file
+	onlyNewManager, err := NewManagerFromConfig(sugarLogger, KeyProviderConfig{MasterKeyID: "2022", MasterKey: "new master key"})
+	require.NoError(t, err)
+	require.Equal(t, inputString, decryptEnvelope(t, onlyNewManager, encrypted, newMetadata))
+}
@@ -0,0 +1,25 @@
+package encrypt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseMasterKeys parses the master keys in the id:key format
+func ParseMasterKeys(entries []string) (map[string]string, error) {
+	keys := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if len(strings.TrimSpace(entry)) == 0 {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("master key must be in id:key format")
+		}
+		if _, ok := keys[parts[0]]; ok {
+			return nil, fmt.Errorf("master key %s is duplicated", parts[0])
+		}
+		keys[parts[0]] = parts[1]
+	}
+	return keys, nil
+}
@@ -1,8 +1,23 @@
 package encrypt
 
-import "io"
+import (
+	"context"
+	"io"
+)
+
+// DefaultKeyID is the ID of the master key that was used before keys had IDs.
+// Files without key ID are decrypted with this key.
+const DefaultKeyID = "default"
+
+// Metadata is the information stored with the file that is needed to decrypt it
+type Metadata struct {
+	Nonce      string
+	KeyID      string
+	WrappedKey string
+}
 
 type Manager interface {
-	Encrypt(input io.Reader) (io.Reader, string, error)
-	Decrypt(input io.Reader, nonceString string) (io.Reader, error)
+	Encrypt(ctx context.Context, input io.Reader) (io.Reader, *Metadata, error)
+	Decrypt(ctx context.Context, input io.Reader, metadata *Metadata) (io.Reader, error)
+	CurrentKeyID() string
 }
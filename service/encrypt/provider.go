@@ -0,0 +1,166 @@
+package encrypt
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	KeyProviderStatic = "static"
+	KeyProviderFile   = "file"
+	KeyProviderVault  = "vault"
+)
+
+// KeyProvider wraps the data keys of the files with the key-encryption-key (KEK).
+// Only the wrapped data keys are stored in the database.
+type KeyProvider interface {
+	// KeyID is the ID of the KEK that is used to wrap new data keys
+	KeyID() string
+	WrapKey(ctx context.Context, dataKey []byte) (string, error)
+	UnwrapKey(ctx context.Context, keyID string, wrappedKey string) ([]byte, error)
+}
+
+type KeyProviderConfig struct {
+	Provider      string
+	MasterKeyID   string
+	MasterKey     string
+	OldMasterKeys map[string]string
+	KeyFile       string
+	Vault         VaultConfig
+}
+
+// NewKeyProvider creates the key provider selected in the config
+func NewKeyProvider(config KeyProviderConfig) (KeyProvider, error) {
+	switch config.Provider {
+	case KeyProviderStatic, "":
+		return NewStaticKeyProvider(config.MasterKeyID, config.MasterKey, config.OldMasterKeys)
+	case KeyProviderFile:
+		return NewFileKeyProvider(config.KeyFile)
+	case KeyProviderVault:
+		return NewVaultKeyProvider(config.Vault)
+	default:
+		return nil, fmt.Errorf("%s key provider is not supported", config.Provider)
+	}
+}
+
+// StaticKeyProvider wraps the data keys with AES-GCM using KEKs derived from the configured master keys
+type StaticKeyProvider struct {
+	currentKeyID string
+	keks         map[string]cipher.AEAD
+}
+
+func NewStaticKeyProvider(currentKeyID string, currentKey string, oldKeys map[string]string) (*StaticKeyProvider, error) {
+	if len(currentKeyID) == 0 {
+		currentKeyID = DefaultKeyID
+	}
+	if len(currentKey) == 0 {
+		return nil, fmt.Errorf("master key %s is empty", currentKeyID)
+	}
+	if key, ok := oldKeys[currentKeyID]; ok && key != currentKey {
+		return nil, fmt.Errorf("master key %s is configured with different keys", currentKeyID)
+	}
+
+	provider := &StaticKeyProvider{currentKeyID: currentKeyID, keks: make(map[string]cipher.AEAD, len(oldKeys)+1)}
+	for keyID, key := range oldKeys {
+		if err := provider.addKey(keyID, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := provider.addKey(currentKeyID, currentKey); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func (p *StaticKeyProvider) addKey(keyID string, masterKey string) error {
+	// derive the KEK from the master key, so it is never the same as the legacy file keys
+	var kek [32]byte
+	kdf := hkdf.New(sha256.New, []byte(masterKey), nil, []byte("cscms-storage key-encryption-key"))
+	if _, err := io.ReadFull(kdf, kek[:]); err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(kek[:])
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	p.keks[keyID] = aead
+	return nil
+}
+
+func (p *StaticKeyProvider) KeyID() string {
+	return p.currentKeyID
+}
+
+func (p *StaticKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, error) {
+	aead := p.keks[p.currentKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	// The key ID is authenticated, so the wrapped key cannot be moved to another key ID
+	wrapped := aead.Seal(nonce, nonce, dataKey, []byte(p.currentKeyID))
+	return base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+func (p *StaticKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrappedKey string) ([]byte, error) {
+	aead, ok := p.keks[keyID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s is not loaded", keyID)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+}
+
+// NewFileKeyProvider reads the master keys from the file, one id:key per line.
+// The first key is used for new files and the others are kept to unwrap the old data keys.
+// Empty lines and lines starting with # are ignored.
+func NewFileKeyProvider(path string) (*StaticKeyProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("key file %s has no key", path)
+	}
+
+	keys, err := ParseMasterKeys(entries)
+	if err != nil {
+		return nil, err
+	}
+	currentKeyID := strings.SplitN(entries[0], ":", 2)[0]
+	return NewStaticKeyProvider(currentKeyID, keys[currentKeyID], keys)
+}
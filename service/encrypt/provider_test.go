@@ -0,0 +1,73 @@
+package encrypt
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStaticKeyProvider(t *testing.T) {
+	provider, err := NewStaticKeyProvider("", EncryptionKey, nil)
+	require.NoError(t, err)
+	require.Equal(t, DefaultKeyID, provider.KeyID())
+
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	wrappedKey, err := provider.WrapKey(context.Background(), dataKey)
+	require.NoError(t, err)
+	require.NotContains(t, wrappedKey, string(dataKey))
+
+	unwrappedKey, err := provider.UnwrapKey(context.Background(), DefaultKeyID, wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, dataKey, unwrappedKey)
+
+	// The wrapped key is bound to the key ID
+	_, err = provider.UnwrapKey(context.Background(), "other", wrappedKey)
+	require.Error(t, err)
+}
+
+func TestStaticKeyProviderRotation(t *testing.T) {
+	oldProvider, err := NewStaticKeyProvider("2021", EncryptionKey, nil)
+	require.NoError(t, err)
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	wrappedKey, err := oldProvider.WrapKey(context.Background(), dataKey)
+	require.NoError(t, err)
+
+	newProvider, err := NewStaticKeyProvider("2022", "new master key", map[string]string{"2021": EncryptionKey})
+	require.NoError(t, err)
+	unwrappedKey, err := newProvider.UnwrapKey(context.Background(), "2021", wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, dataKey, unwrappedKey)
+
+	_, err = NewStaticKeyProvider("2022", "", nil)
+	require.Error(t, err)
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte("# current key first\n2022:new master key\n\n2021:"+EncryptionKey+"\n"), 0600))
+
+	provider, err := NewFileKeyProvider(path)
+	require.NoError(t, err)
+	require.Equal(t, "2022", provider.KeyID())
+	require.Contains(t, provider.keks, "2021")
+
+	emptyPath := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(emptyPath, []byte("# no key\n"), 0600))
+	_, err = NewFileKeyProvider(emptyPath)
+	require.Error(t, err)
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	provider, err := NewKeyProvider(KeyProviderConfig{MasterKey: EncryptionKey})
+	require.NoError(t, err)
+	require.IsType(t, &StaticKeyProvider{}, provider)
+
+	provider, err = NewKeyProvider(KeyProviderConfig{Provider: KeyProviderVault, Vault: VaultConfig{Address: "http://127.0.0.1:8200", KeyName: "cscms"}})
+	require.NoError(t, err)
+	require.IsType(t, &VaultKeyProvider{}, provider)
+
+	_, err = NewKeyProvider(KeyProviderConfig{Provider: "unknown"})
+	require.Error(t, err)
+}
@@ -1,9 +1,11 @@
 package encrypt
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"github.com/minio/sio"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/hkdf"
@@ -11,27 +13,47 @@ import (
 )
 
 type SIOEncryptionManager struct {
-	log       *zap.SugaredLogger
-	masterKey string
+	log          *zap.SugaredLogger
+	currentKeyID string
+	masterKeys   map[string]string
 }
 
-func NewSIOEncryptionManager(l *zap.SugaredLogger, key string) *SIOEncryptionManager {
-	return &SIOEncryptionManager{
-		log:       l,
-		masterKey: key,
+// NewSIOEncryptionManager creates the manager that encrypts new files with the current key.
+// The old keys are only used to decrypt files that are not re-keyed yet.
+func NewSIOEncryptionManager(l *zap.SugaredLogger, currentKeyID string, currentKey string, oldKeys map[string]string) (*SIOEncryptionManager, error) {
+	if len(currentKeyID) == 0 {
+		currentKeyID = DefaultKeyID
+	}
+	masterKeys := make(map[string]string, len(oldKeys)+1)
+	for keyID, key := range oldKeys {
+		masterKeys[keyID] = key
+	}
+	if key, ok := masterKeys[currentKeyID]; ok && key != currentKey {
+		return nil, fmt.Errorf("master key %s is configured with different keys", currentKeyID)
 	}
+	masterKeys[currentKeyID] = currentKey
+
+	return &SIOEncryptionManager{
+		log:          l,
+		currentKeyID: currentKeyID,
+		masterKeys:   masterKeys,
+	}, nil
+}
+
+func (m *SIOEncryptionManager) CurrentKeyID() string {
+	return m.currentKeyID
 }
 
-func (m *SIOEncryptionManager) Encrypt(input io.Reader) (io.Reader, string, error) {
+func (m *SIOEncryptionManager) Encrypt(ctx context.Context, input io.Reader) (io.Reader, *Metadata, error) {
 	// the master key used to derive encryption keys
-	masterKey := []byte(m.masterKey)
+	masterKey := []byte(m.masterKeys[m.currentKeyID])
 
 	// generate a random nonce to derive an encryption key from the master key
 	// this nonce must be saved to be able to decrypt the data again
 	var nonce [32]byte
 	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
 		m.log.Errorw("Failed to read random data", "error", err)
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	// derive an encryption key from the master key and the nonce
@@ -39,36 +61,46 @@ func (m *SIOEncryptionManager) Encrypt(input io.Reader) (io.Reader, string, erro
 	kdf := hkdf.New(sha256.New, masterKey, nonce[:], nil)
 	if _, err := io.ReadFull(kdf, key[:]); err != nil {
 		m.log.Errorw("Failed to derive encryption key", "error", err)
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	encrypted, err := sio.EncryptReader(input, sio.Config{Key: key[:]})
 	if err != nil {
 		m.log.Errorw("Failed to encrypted reader", "error", err)
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return encrypted, hex.EncodeToString(nonce[:]), nil
+	return encrypted, &Metadata{Nonce: hex.EncodeToString(nonce[:]), KeyID: m.currentKeyID}, nil
 }
 
-func (m *SIOEncryptionManager) Decrypt(input io.Reader, nonceString string) (io.Reader, error) {
+func (m *SIOEncryptionManager) Decrypt(ctx context.Context, input io.Reader, metadata *Metadata) (io.Reader, error) {
 	// the master key used to derive encryption keys
-	masterKey := []byte(m.masterKey)
+	keyID := metadata.KeyID
+	if len(keyID) == 0 {
+		keyID = DefaultKeyID
+	}
+	key, ok := m.masterKeys[keyID]
+	if !ok {
+		err := fmt.Errorf("master key %s is not loaded", keyID)
+		m.log.Errorw("Failed to find master key", "error", err)
+		return nil, err
+	}
+	masterKey := []byte(key)
 
 	// the nonce used to derive the encryption key
-	nonce, err := hex.DecodeString(nonceString)
+	nonce, err := hex.DecodeString(metadata.Nonce)
 	if err != nil {
 		m.log.Errorw("Failed to decode hex string to byte", "error", err)
 		return nil, err
 	}
 
 	// derive the encryption key from the master key and the nonce
-	var key [32]byte
+	var fileKey [32]byte
 	kdf := hkdf.New(sha256.New, masterKey, nonce, nil)
-	if _, err := io.ReadFull(kdf, key[:]); err != nil {
+	if _, err := io.ReadFull(kdf, fileKey[:]); err != nil {
 		m.log.Errorw("Failed to derive encryption key", "error", err)
 		return nil, err
 	}
 
-	return sio.DecryptReader(input, sio.Config{Key: key[:]})
+	return sio.DecryptReader(input, sio.Config{Key: fileKey[:]})
 }
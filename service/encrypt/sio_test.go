@@ -1,6 +1,8 @@
 package encrypt
 
 import (
+	"bytes"
+	"context"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"io"
@@ -18,12 +20,14 @@ func TestEncryptionAndDecryption(t *testing.T) {
 	logger, err := zap.NewDevelopment()
 	require.NoError(t, err)
 	sugarLogger := logger.Sugar()
-	sioManager := NewSIOEncryptionManager(sugarLogger, EncryptionKey)
+	sioManager, err := NewSIOEncryptionManager(sugarLogger, "", EncryptionKey, nil)
+	require.NoError(t, err)
 
 	// Encrypt the reader
-	encryptedReader, nonce, err := sioManager.Encrypt(inputReader)
+	encryptedReader, metadata, err := sioManager.Encrypt(context.Background(), inputReader)
 	require.NoError(t, err)
-	require.NotEmpty(t, nonce)
+	require.NotEmpty(t, metadata.Nonce)
+	require.Equal(t, DefaultKeyID, metadata.KeyID)
 
 	// Check the encrypted reader
 	b := new(strings.Builder)
@@ -33,8 +37,66 @@ func TestEncryptionAndDecryption(t *testing.T) {
 
 	// Decrypt the reader (Somehow didn't works)
 	//decryptedWriter := new(strings.Builder)
-	//err = sioManager.Decrypt(encryptedReader, nonce, decryptedWriter)
+	//err = sioManager.Decrypt(context.Background(), encryptedReader, nonce, decryptedWriter)
 	//sugarLogger.Info("n", decryptedWriter.Len())
 	//require.NoError(t, err)
 	//require.Equal(t, inputString, decryptedWriter.String())
 }
+
+func encryptString(t *testing.T, manager *SIOEncryptionManager, input string) ([]byte, *Metadata) {
+	encryptedReader, metadata, err := manager.Encrypt(context.Background(), strings.NewReader(input))
+	require.NoError(t, err)
+	encrypted, err := io.ReadAll(encryptedReader)
+	require.NoError(t, err)
+	return encrypted, metadata
+}
+
+func decryptString(t *testing.T, manager *SIOEncryptionManager, encrypted []byte, metadata *Metadata) string {
+	decryptedReader, err := manager.Decrypt(context.Background(), bytes.NewReader(encrypted), metadata)
+	require.NoError(t, err)
+	decrypted, err := io.ReadAll(decryptedReader)
+	require.NoError(t, err)
+	return string(decrypted)
+}
+
+func TestKeyRotation(t *testing.T) {
+	inputString := "Hello World, This should be encrypted"
+	sugarLogger := zap.NewNop().Sugar()
+
+	oldManager, err := NewSIOEncryptionManager(sugarLogger, "", EncryptionKey, nil)
+	require.NoError(t, err)
+	oldEncrypted, oldMetadata := encryptString(t, oldManager, inputString)
+
+	newManager, err := NewSIOEncryptionManager(sugarLogger, "2022", "new master key", map[string]string{DefaultKeyID: EncryptionKey})
+	require.NoError(t, err)
+	require.Equal(t, "2022", newManager.CurrentKeyID())
+	newEncrypted, newMetadata := encryptString(t, newManager, inputString)
+	require.Equal(t, "2022", newMetadata.KeyID)
+
+	// Both the file with the old key and the file without key ID can still be decrypted
+	require.Equal(t, inputString, decryptString(t, newManager, oldEncrypted, oldMetadata))
+	require.Equal(t, inputString, decryptString(t, newManager, oldEncrypted, &Metadata{Nonce: oldMetadata.Nonce}))
+	require.Equal(t, inputString, decryptString(t, newManager, newEncrypted, newMetadata))
+
+	// The old manager does not know the new key
+	_, err = oldManager.Decrypt(context.Background(), bytes.NewReader(newEncrypted), newMetadata)
+	require.Error(t, err)
+}
+
+func TestConflictingMasterKey(t *testing.T) {
+	_, err := NewSIOEncryptionManager(zap.NewNop().Sugar(), "2022", "new master key", map[string]string{"2022": EncryptionKey})
+	require.Error(t, err)
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	keys, err := ParseMasterKeys([]string{"default:" + EncryptionKey, "2022:key:with:colon", ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{DefaultKeyID: EncryptionKey, "2022": "key:with:colon"}, keys)
+
+	_, err = ParseMasterKeys([]string{"no-key-id"})
+	require.Error(t, err)
+	_, err = ParseMasterKeys([]string{":key"})
+	require.Error(t, err)
+	_, err = ParseMasterKeys([]string{"a:key", "a:other"})
+	require.Error(t, err)
+}
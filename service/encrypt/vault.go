@@ -0,0 +1,116 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type VaultConfig struct {
+	Address string
+	Token   string
+	Mount   string
+	KeyName string
+	// Timeout limits each request to Vault, 10 seconds if it is zero
+	Timeout time.Duration
+}
+
+// VaultKeyProvider wraps the data keys with the transit secrets engine of Vault.
+// The KEK never leaves Vault and the key ID is the name of the transit key.
+type VaultKeyProvider struct {
+	client  *http.Client
+	address string
+	token   string
+	mount   string
+	keyName string
+}
+
+type vaultResponse struct {
+	Data struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func NewVaultKeyProvider(config VaultConfig) (*VaultKeyProvider, error) {
+	if len(config.Address) == 0 || len(config.KeyName) == 0 {
+		return nil, fmt.Errorf("vault address and key name must be provided")
+	}
+	mount := config.Mount
+	if len(mount) == 0 {
+		mount = "transit"
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return &VaultKeyProvider{
+		client:  &http.Client{Timeout: timeout},
+		address: strings.TrimSuffix(config.Address, "/"),
+		token:   config.Token,
+		mount:   strings.Trim(mount, "/"),
+		keyName: config.KeyName,
+	}, nil
+}
+
+func (p *VaultKeyProvider) KeyID() string {
+	return p.keyName
+}
+
+func (p *VaultKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, error) {
+	response, err := p.request(ctx, "encrypt", p.keyName, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(dataKey),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Data.Ciphertext) == 0 {
+		return "", fmt.Errorf("vault returned empty ciphertext")
+	}
+	return response.Data.Ciphertext, nil
+}
+
+func (p *VaultKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrappedKey string) ([]byte, error) {
+	response, err := p.request(ctx, "decrypt", keyID, map[string]string{
+		"ciphertext": wrappedKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+func (p *VaultKeyProvider) request(ctx context.Context, operation string, keyName string, body map[string]string) (*vaultResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", p.address, p.mount, operation, keyName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.token)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var response vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil && res.StatusCode == http.StatusOK {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault %s failed with status %d: %s", operation, res.StatusCode, strings.Join(response.Errors, ", "))
+	}
+	return &response, nil
+}
@@ -0,0 +1,83 @@
+package encrypt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTransitStandIn mocks the encrypt and decrypt endpoints of the Vault transit engine
+func newTransitStandIn(t *testing.T, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch {
+		case r.URL.Path == "/v1/transit/encrypt/cscms":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]},
+			})
+		case strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/cscms"):
+			if !strings.HasPrefix(body["ciphertext"], "vault:v1:") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["invalid ciphertext"]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestVaultKeyProvider(t *testing.T) {
+	server := newTransitStandIn(t, "vault-token")
+	defer server.Close()
+
+	provider, err := NewVaultKeyProvider(VaultConfig{Address: server.URL + "/", Token: "vault-token", KeyName: "cscms"})
+	require.NoError(t, err)
+	require.Equal(t, "cscms", provider.KeyID())
+
+	dataKey := []byte("0123456789abcdef0123456789abcdef")
+	wrappedKey, err := provider.WrapKey(context.Background(), dataKey)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(wrappedKey, "vault:v1:"))
+
+	unwrappedKey, err := provider.UnwrapKey(context.Background(), "cscms", wrappedKey)
+	require.NoError(t, err)
+	require.Equal(t, dataKey, unwrappedKey)
+
+	_, err = provider.UnwrapKey(context.Background(), "cscms", "invalid")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid ciphertext")
+
+	_, err = provider.UnwrapKey(context.Background(), "other", wrappedKey)
+	require.Error(t, err)
+}
+
+func TestVaultKeyProviderForbidden(t *testing.T) {
+	server := newTransitStandIn(t, "vault-token")
+	defer server.Close()
+
+	provider, err := NewVaultKeyProvider(VaultConfig{Address: server.URL, Token: "wrong-token", KeyName: "cscms"})
+	require.NoError(t, err)
+	_, err = provider.WrapKey(context.Background(), []byte("0123456789abcdef0123456789abcdef"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "permission denied")
+
+	_, err = NewVaultKeyProvider(VaultConfig{Address: server.URL})
+	require.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package event
+
+import "time"
+
+// DownloadEvent is sent when the content of a file is sent to the client
+type DownloadEvent struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	FileID    string    `json:"file_id"`
+	Token     string    `json:"token"`
+	Filename  string    `json:"filename"`
+	Bytes     uint64    `json:"bytes"`
+	// UserID is the ID of the logged in user that downloads the file, 0 for anonymous user
+	UserID    uint   `json:"user_id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+type Sink interface {
+	SendDownload(event *DownloadEvent) error
+}
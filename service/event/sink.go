@@ -0,0 +1,91 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"sync"
+)
+
+const (
+	SinkNone = "none"
+	SinkLog  = "log"
+	SinkFile = "file"
+)
+
+// NewSink creates the sink by its type, path is only used by the file sink
+func NewSink(l *zap.SugaredLogger, sinkType string, path string) (Sink, error) {
+	switch sinkType {
+	case SinkNone:
+		return &NopSink{}, nil
+	case SinkLog:
+		return NewLogSink(l), nil
+	case SinkFile:
+		return NewFileSink(path)
+	default:
+		return nil, fmt.Errorf("unknown event sink %s", sinkType)
+	}
+}
+
+// NopSink drops the events
+type NopSink struct{}
+
+func (s *NopSink) SendDownload(*DownloadEvent) error {
+	return nil
+}
+
+// LogSink writes the events to the log
+type LogSink struct {
+	log *zap.SugaredLogger
+}
+
+func NewLogSink(l *zap.SugaredLogger) *LogSink {
+	return &LogSink{log: l.Named("download")}
+}
+
+func (s *LogSink) SendDownload(event *DownloadEvent) error {
+	s.log.Infow("file downloaded",
+		"request_id", event.RequestID,
+		"file_id", event.FileID,
+		"token", event.Token,
+		"filename", event.Filename,
+		"bytes", event.Bytes,
+		"user_id", event.UserID,
+		"ip", event.IP,
+		"user_agent", event.UserAgent,
+	)
+	return nil
+}
+
+// FileSink appends the events to the file as JSON lines
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("path of the event file must be provided")
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) SendDownload(event *DownloadEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "downloads.jsonl")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	events := []*DownloadEvent{
+		{Time: time.Now().UTC(), RequestID: "request-1", FileID: "file-1", Token: "token-1", Bytes: 10},
+		{Time: time.Now().UTC(), RequestID: "request-2", FileID: "file-2", Token: "token-2", Bytes: 20, UserID: 1},
+	}
+	for _, e := range events {
+		require.NoError(t, sink.SendDownload(e))
+	}
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for _, expected := range events {
+		require.True(t, scanner.Scan())
+		var e DownloadEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		require.Equal(t, expected.RequestID, e.RequestID)
+		require.Equal(t, expected.FileID, e.FileID)
+		require.Equal(t, expected.Bytes, e.Bytes)
+		require.Equal(t, expected.UserID, e.UserID)
+	}
+	require.False(t, scanner.Scan())
+}
+
+func TestNewSink(t *testing.T) {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+
+	sink, err := NewSink(logger.Sugar(), SinkLog, "")
+	require.NoError(t, err)
+	require.NoError(t, sink.SendDownload(&DownloadEvent{FileID: "file"}))
+
+	_, err = NewSink(logger.Sugar(), SinkFile, "")
+	require.Error(t, err)
+	_, err = NewSink(logger.Sugar(), "kafka", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,214 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("remote address is not allowed")
+	ErrTooLarge         = errors.New("remote file is too large")
+)
+
+// blockedNetworks are the networks that are not routable on the public internet
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+type HTTPFetchManager struct {
+	log     *zap.SugaredLogger
+	client  *http.Client
+	maxSize int64
+	// allowPrivate disables the SSRF protection, only used in tests
+	allowPrivate bool
+}
+
+func NewHTTPFetchManager(l *zap.SugaredLogger, maxSize int64, timeout time.Duration) *HTTPFetchManager {
+	m := &HTTPFetchManager{
+		log:     l,
+		maxSize: maxSize,
+	}
+	dialer := NewDialer(m.checkAddress)
+	m.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Proxy is not used, so the dialer always checks the real remote address
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return checkScheme(req.URL)
+		},
+	}
+	return m
+}
+
+func (m *HTTPFetchManager) Fetch(ctx context.Context, rawURL string) (*RemoteFile, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "cscms-storage")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenAddress) {
+			return nil, ErrForbiddenAddress
+		}
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("remote server responded with status %d", resp.StatusCode)
+	}
+	if m.maxSize > 0 && resp.ContentLength > m.maxSize {
+		_ = resp.Body.Close()
+		return nil, ErrTooLarge
+	}
+
+	return &RemoteFile{
+		Body:        &limitedReadCloser{body: resp.Body, remaining: m.maxSize, limited: m.maxSize > 0},
+		Filename:    remoteFilename(resp),
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}, nil
+}
+
+func (m *HTTPFetchManager) checkAddress(address string) error {
+	if m.allowPrivate {
+		return nil
+	}
+	return CheckPublicAddress(address)
+}
+
+// NewDialer creates the dialer that only connects to the address allowed by the check.
+// The address is checked after DNS resolution to prevent DNS rebinding.
+func NewDialer(checkAddress func(address string) error) *net.Dialer {
+	return &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		},
+	}
+}
+
+// CheckPublicAddress returns ErrForbiddenAddress if the host:port address is not public
+func CheckPublicAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// IsPublicIP reports whether the ip is routable on the public internet
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s scheme is not supported", u.Scheme)
+	}
+	return nil
+}
+
+// remoteFilename gets the file name from Content-Disposition or the URL path
+func remoteFilename(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], "\\", "/")); len(params["filename"]) > 0 && name != "/" && name != "." {
+			return name
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return "download"
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// limitedReadCloser returns ErrTooLarge once more than the allowed bytes are read
+type limitedReadCloser struct {
+	body      io.ReadCloser
+	remaining int64
+	limited   bool
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if r.limited {
+		r.remaining -= int64(n)
+		if r.remaining < 0 {
+			return n, ErrTooLarge
+		}
+	}
+	return n, err
+}
+
+func (r *limitedReadCloser) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,97 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fileContent = "When I was a young boy, my father took me into the city to see a marching band"
+
+func createHTTPFetchManager(t *testing.T, maxSize int64) *HTTPFetchManager {
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	return NewHTTPFetchManager(logger.Sugar(), maxSize, time.Minute)
+}
+
+func createTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/attachment" {
+			w.Header().Set("Content-Disposition", `attachment; filename="report.txt"`)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = fmt.Fprint(w, fileContent)
+	}))
+}
+
+func TestFetch(t *testing.T) {
+	server := createTestServer()
+	defer server.Close()
+	fetchManager := createHTTPFetchManager(t, 1<<20)
+	fetchManager.allowPrivate = true
+
+	remoteFile, err := fetchManager.Fetch(context.Background(), server.URL+"/files/release.tar.gz")
+	require.NoError(t, err)
+	defer remoteFile.Body.Close()
+	require.Equal(t, "release.tar.gz", remoteFile.Filename)
+	require.Equal(t, "text/plain", remoteFile.ContentType)
+
+	content := new(strings.Builder)
+	_, err = io.Copy(content, remoteFile.Body)
+	require.NoError(t, err)
+	require.Equal(t, fileContent, content.String())
+}
+
+func TestFetchFilenameFromContentDisposition(t *testing.T) {
+	server := createTestServer()
+	defer server.Close()
+	fetchManager := createHTTPFetchManager(t, 1<<20)
+	fetchManager.allowPrivate = true
+
+	remoteFile, err := fetchManager.Fetch(context.Background(), server.URL+"/attachment")
+	require.NoError(t, err)
+	defer remoteFile.Body.Close()
+	require.Equal(t, "report.txt", remoteFile.Filename)
+}
+
+func TestFetchTooLarge(t *testing.T) {
+	server := createTestServer()
+	defer server.Close()
+	fetchManager := createHTTPFetchManager(t, 10)
+	fetchManager.allowPrivate = true
+
+	_, err := fetchManager.Fetch(context.Background(), server.URL)
+	require.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestFetchPrivateAddress(t *testing.T) {
+	server := createTestServer()
+	defer server.Close()
+	fetchManager := createHTTPFetchManager(t, 1<<20)
+
+	_, err := fetchManager.Fetch(context.Background(), server.URL)
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+}
+
+func TestFetchUnsupportedScheme(t *testing.T) {
+	fetchManager := createHTTPFetchManager(t, 1<<20)
+	_, err := fetchManager.Fetch(context.Background(), "file:///etc/passwd")
+	require.Error(t, err)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1", "::ffff:127.0.0.1", "0.0.0.0"} {
+		require.False(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		require.True(t, IsPublicIP(net.ParseIP(ip)), ip)
+	}
+}
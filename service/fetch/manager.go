@@ -0,0 +1,18 @@
+package fetch
+
+import (
+	"context"
+	"io"
+)
+
+type Manager interface {
+	Fetch(ctx context.Context, rawURL string) (*RemoteFile, error)
+}
+
+type RemoteFile struct {
+	Body        io.ReadCloser
+	Filename    string
+	ContentType string
+	// Size is the size reported by the remote server, or -1 if unknown
+	Size int64
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+)
+
+// ErrUnsupported is returned by the check that is not supported on the platform
+var ErrUnsupported = errors.New("check is not supported on this platform")
+
+// DatabaseChecker pings the database of gorm
+func DatabaseChecker(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// WritableDirChecker creates and removes a temporary file in the directory
+func WritableDirChecker(path string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		file, err := os.CreateTemp(path, ".health-*")
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write([]byte("ok")); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return err
+		}
+		if err := file.Close(); err != nil {
+			_ = os.Remove(file.Name())
+			return err
+		}
+		return os.Remove(file.Name())
+	})
+}
+
+// DiskSpaceChecker checks that the file system of the path has at least the free bytes and the free percent.
+// The zero threshold is not checked.
+func DiskSpaceChecker(path string, minFreeBytes uint64, minFreePercent float64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		free, total, err := diskSpace(path)
+		if err != nil {
+			return err
+		}
+		if minFreeBytes > 0 && free < minFreeBytes {
+			return fmt.Errorf("%d bytes free, less than %d bytes", free, minFreeBytes)
+		}
+		if minFreePercent > 0 && total > 0 {
+			if percent := float64(free) / float64(total) * 100; percent < minFreePercent {
+				return fmt.Errorf("%.1f%% free, less than %.1f%%", percent, minFreePercent)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package health
+
+func diskSpace(string) (uint64, uint64, error) {
+	return 0, 0, ErrUnsupported
+}
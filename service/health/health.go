@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type namedChecker struct {
+	name    string
+	checker Checker
+}
+
+// Health runs the checks of the dependencies concurrently, every check is limited by the timeout
+type Health struct {
+	timeout  time.Duration
+	checkers []namedChecker
+}
+
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Add adds the check of the dependency, it must not be called after the checks are run
+func (h *Health) Add(name string, checker Checker) *Health {
+	h.checkers = append(h.checkers, namedChecker{name: name, checker: checker})
+	return h
+}
+
+func (h *Health) Run(ctx context.Context) *Report {
+	report := &Report{
+		Status: StatusUp,
+		Checks: make(map[string]CheckResult, len(h.checkers)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range h.checkers {
+		wg.Add(1)
+		go func(c namedChecker) {
+			defer wg.Done()
+			result := h.check(ctx, c.checker)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+func (h *Health) check(ctx context.Context, checker Checker) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- checker.Check(ctx)
+	}()
+	// The check that ignores the context is reported as down after the timeout
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusUp, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	h := New(50*time.Millisecond).
+		Add("up", CheckerFunc(func(context.Context) error { return nil })).
+		Add("down", CheckerFunc(func(context.Context) error { return errors.New("connection refused") })).
+		// The check is stopped by the timeout even if it ignores the context
+		Add("slow", CheckerFunc(func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}))
+
+	report := h.Run(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, StatusUp, report.Checks["up"].Status)
+	require.Equal(t, StatusDown, report.Checks["down"].Status)
+	require.Equal(t, "connection refused", report.Checks["down"].Error)
+	require.Equal(t, StatusDown, report.Checks["slow"].Status)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+
+	report = New(time.Second).Add("up", CheckerFunc(func(context.Context) error { return nil })).Run(context.Background())
+	require.Equal(t, StatusUp, report.Status)
+}
+
+func TestWritableDirChecker(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WritableDirChecker(dir).Check(context.Background()))
+	// The temporary file is removed
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 0)
+
+	require.Error(t, WritableDirChecker(filepath.Join(dir, "missing")).Check(context.Background()))
+}
+
+func TestDiskSpaceChecker(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, DiskSpaceChecker(dir, 0, 0).Check(context.Background()))
+	require.NoError(t, DiskSpaceChecker(dir, 1, 0).Check(context.Background()))
+	require.Error(t, DiskSpaceChecker(dir, 1<<62, 0).Check(context.Background()))
+	require.Error(t, DiskSpaceChecker(dir, 0, 101).Check(context.Background()))
+	require.Error(t, DiskSpaceChecker(filepath.Join(dir, "missing"), 0, 0).Check(context.Background()))
+}
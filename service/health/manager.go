@@ -0,0 +1,33 @@
+package health
+
+import "context"
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Checker checks a single dependency, the dependency is down if it returns the error
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts the function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Report is the status of all dependencies, the status is up only if all dependencies are up
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	// Error is not sent to the client, it can contain the addresses and paths of the dependency
+	Error      string `json:"-"`
+	DurationMs int64  `json:"duration_ms"`
+}
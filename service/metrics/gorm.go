@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+const gormStartKey = "metrics:start"
+
+// GormPlugin records the latency and errors of the queries run by gorm
+type GormPlugin struct{}
+
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	registers := []func() error{
+		func() error { return callback.Create().Before("gorm:create").Register("metrics:before_create", before) },
+		func() error {
+			return callback.Create().After("gorm:create").Register("metrics:after_create", after("create"))
+		},
+		func() error { return callback.Query().Before("gorm:query").Register("metrics:before_query", before) },
+		func() error {
+			return callback.Query().After("gorm:query").Register("metrics:after_query", after("query"))
+		},
+		func() error { return callback.Update().Before("gorm:update").Register("metrics:before_update", before) },
+		func() error {
+			return callback.Update().After("gorm:update").Register("metrics:after_update", after("update"))
+		},
+		func() error { return callback.Delete().Before("gorm:delete").Register("metrics:before_delete", before) },
+		func() error {
+			return callback.Delete().After("gorm:delete").Register("metrics:after_delete", after("delete"))
+		},
+		func() error { return callback.Row().Before("gorm:row").Register("metrics:before_row", before) },
+		func() error { return callback.Row().After("gorm:row").Register("metrics:after_row", after("row")) },
+		func() error { return callback.Raw().Before("gorm:raw").Register("metrics:before_raw", before) },
+		func() error { return callback.Raw().After("gorm:raw").Register("metrics:after_raw", after("raw")) },
+	}
+	for _, register := range registers {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
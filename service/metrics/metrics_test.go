@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFiberMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(NewFiberMiddleware())
+	app.Get("/files/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "missing" {
+			return fiber.NewError(fiber.StatusNotFound, "not found")
+		}
+		return c.SendString("ok")
+	})
+	app.Get("/metrics", Handler("secret"))
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/files/:id", "200"))
+	beforeNotFound := testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/files/:id", "404"))
+	for _, path := range []string{"/files/a", "/files/b", "/files/missing"} {
+		res, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+	require.Equal(t, before+2, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/files/:id", "200")))
+	require.Equal(t, beforeNotFound+1, testutil.ToFloat64(HTTPRequests.WithLabelValues("GET", "/files/:id", "404")))
+
+	// Metrics endpoint requires the token
+	res, err := app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `cscms_http_requests_total{method="GET",route="/files/:id",status="200"}`)
+}
+
+func TestTransferReader(t *testing.T) {
+	beforeBytes := testutil.ToFloat64(TransferBytes.WithLabelValues(Download))
+	beforeActive := testutil.ToFloat64(ActiveTransfers.WithLabelValues(Download))
+
+	reader := NewTransferReader(io.NopCloser(strings.NewReader("hello world")), Download)
+	require.Equal(t, beforeActive+1, testutil.ToFloat64(ActiveTransfers.WithLabelValues(Download)))
+	_, err := io.Copy(io.Discard, reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.NoError(t, reader.Close())
+
+	require.Equal(t, beforeBytes+11, testutil.ToFloat64(TransferBytes.WithLabelValues(Download)))
+	require.Equal(t, beforeActive, testutil.ToFloat64(ActiveTransfers.WithLabelValues(Download)))
+}
+
+type failingFileManager struct {
+	FileManager
+}
+
+func (m *failingFileManager) DeleteFile(context.Context, string) error {
+	return errors.New("unable to delete")
+}
+
+func TestFileManager(t *testing.T) {
+	manager := NewFileManager("test", &failingFileManager{})
+	before := testutil.ToFloat64(StorageOperationErrors.WithLabelValues("test", "delete"))
+	require.Error(t, manager.DeleteFile(context.Background(), "file"))
+	require.Equal(t, before+1, testutil.ToFloat64(StorageOperationErrors.WithLabelValues("test", "delete")))
+	require.Equal(t, 1, testutil.CollectAndCount(StorageOperationDuration, "cscms_storage_operation_duration_seconds"))
+}
+
+type testRecord struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(&GormPlugin{}))
+	require.NoError(t, db.AutoMigrate(&testRecord{}))
+
+	require.NoError(t, db.Create(&testRecord{Name: "test"}).Error)
+	var records []testRecord
+	require.NoError(t, db.Find(&records).Error)
+	require.Error(t, db.Table("not_exist").Find(&records).Error)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(DBQueryErrors.WithLabelValues("query", "not_exist")))
+	require.GreaterOrEqual(t, testutil.CollectAndCount(DBQueryDuration, "cscms_db_query_duration_seconds"), 2)
+}
+
+func TestPushCleanerResult(t *testing.T) {
+	var pushed string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/metrics/job/cleaner", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		pushed = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, PushCleanerResult(server.URL, 3, false, time.Second))
+	require.Contains(t, pushed, "cscms_cleaner_deleted_files")
+}
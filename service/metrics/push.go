@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"time"
+)
+
+// PushCleanerResult pushes the result of the cleaner run to the Pushgateway,
+// because the cleaner exits before it can be scraped
+func PushCleanerResult(gatewayURL string, deletedCount int, failed bool, duration time.Duration) error {
+	deleted := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cleaner_deleted_files",
+		Help:      "Number of files deleted by the last cleaner run.",
+	})
+	deleted.Set(float64(deletedCount))
+
+	failure := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cleaner_failed",
+		Help:      "1 if the last cleaner run had any failure.",
+	})
+	if failed {
+		failure.Set(1)
+	}
+
+	runDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cleaner_duration_seconds",
+		Help:      "Duration of the last cleaner run.",
+	})
+	runDuration.Set(duration.Seconds())
+
+	lastRun := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cleaner_last_run_timestamp_seconds",
+		Help:      "Time of the last cleaner run.",
+	})
+	lastRun.SetToCurrentTime()
+
+	return push.New(gatewayURL, "cleaner").
+		Collector(deleted).
+		Collector(failure).
+		Collector(runDuration).
+		Collector(lastRun).
+		Push()
+}
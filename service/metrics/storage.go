@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"io"
+	"time"
+)
+
+func observeStorage(backend string, operation string, start time.Time, err error) {
+	StorageOperationDuration.WithLabelValues(backend, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		StorageOperationErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// FileManager records the latency and errors of the wrapped storage.FileManager
+type FileManager struct {
+	backend string
+	manager storage.FileManager
+}
+
+func NewFileManager(backend string, manager storage.FileManager) *FileManager {
+	return &FileManager{backend: backend, manager: manager}
+}
+
+func (m *FileManager) OpenFile(ctx context.Context, fileName string) (io.Reader, error) {
+	start := time.Now()
+	file, err := m.manager.OpenFile(ctx, fileName)
+	observeStorage(m.backend, "open", start, err)
+	return file, err
+}
+
+// WriteToNewFile records the whole write, including reading the content from the reader
+func (m *FileManager) WriteToNewFile(ctx context.Context, fileName string, reader io.Reader) error {
+	start := time.Now()
+	err := m.manager.WriteToNewFile(ctx, fileName, reader)
+	observeStorage(m.backend, "write", start, err)
+	return err
+}
+
+func (m *FileManager) Exist(ctx context.Context, fileName string) (bool, error) {
+	start := time.Now()
+	exist, err := m.manager.Exist(ctx, fileName)
+	observeStorage(m.backend, "exist", start, err)
+	return exist, err
+}
+
+func (m *FileManager) ListFiles(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	files, err := m.manager.ListFiles(ctx)
+	observeStorage(m.backend, "list", start, err)
+	return files, err
+}
+
+func (m *FileManager) DeleteFile(ctx context.Context, fileName string) error {
+	start := time.Now()
+	err := m.manager.DeleteFile(ctx, fileName)
+	observeStorage(m.backend, "delete", start, err)
+	return err
+}
+
+func (m *FileManager) RenameFile(ctx context.Context, oldName string, newName string) error {
+	start := time.Now()
+	err := m.manager.RenameFile(ctx, oldName, newName)
+	observeStorage(m.backend, "rename", start, err)
+	return err
+}
+
+// ImageManager records the latency and errors of the wrapped storage.ImageManager
+type ImageManager struct {
+	backend string
+	manager storage.ImageManager
+}
+
+func NewImageManager(backend string, manager storage.ImageManager) *ImageManager {
+	return &ImageManager{backend: backend, manager: manager}
+}
+
+func (m *ImageManager) UploadImage(ctx context.Context, fileName string, mimeType string, file io.ReadSeekCloser) error {
+	start := time.Now()
+	err := m.manager.UploadImage(ctx, fileName, mimeType, file)
+	observeStorage(m.backend, "upload_image", start, err)
+	return err
+}
+
+func (m *ImageManager) DeleteImage(ctx context.Context, fileName string) error {
+	start := time.Now()
+	err := m.manager.DeleteImage(ctx, fileName)
+	observeStorage(m.backend, "delete_image", start, err)
+	return err
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"io"
+	"sync"
+)
+
+type transferReader struct {
+	reader    io.ReadCloser
+	direction string
+	once      sync.Once
+}
+
+// NewTransferReader counts the bytes read as transferred in the direction.
+// The transfer is active until the reader is closed.
+func NewTransferReader(reader io.ReadCloser, direction string) io.ReadCloser {
+	ActiveTransfers.WithLabelValues(direction).Inc()
+	return &transferReader{reader: reader, direction: direction}
+}
+
+func (r *transferReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	TransferBytes.WithLabelValues(r.direction).Add(float64(n))
+	return n, err
+}
+
+func (r *transferReader) Close() error {
+	r.once.Do(func() {
+		ActiveTransfers.WithLabelValues(r.direction).Dec()
+	})
+	return r.reader.Close()
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+// Limiter limits the clients with fixed windows counted in the store
+type Limiter struct {
+	log   *zap.SugaredLogger
+	store Store
+	key   KeyGenerator
+}
+
+func New(l *zap.SugaredLogger, store Store, key KeyGenerator) *Limiter {
+	return &Limiter{
+		log:   l,
+		store: store,
+		key:   key,
+	}
+}
+
+// Handler rejects the requests over the limits of the rule with 429.
+// The request body is counted to the bandwidth before the handler and the response body after it,
+// the streamed response without length is not counted.
+// The requests are allowed if the store is not available, so the store cannot take down the server.
+func (l *Limiter) Handler(rule Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if rule.Max <= 0 && rule.Bandwidth <= 0 {
+			return c.Next()
+		}
+
+		windowStart := time.Now().UTC().Truncate(rule.Window)
+		resetAt := windowStart.Add(rule.Window)
+		key := fmt.Sprintf("%s:%s:%d", rule.Name, l.key(c), windowStart.Unix())
+		// The fiber context is reused after the handler returns, so the context is taken before
+		ctx := c.UserContext()
+
+		if rule.Max > 0 {
+			hits, err := l.store.Increment(ctx, key+":requests", 1, resetAt)
+			if err != nil {
+				l.log.Errorw("unable to count request for rate limit", "error", err, "rule", rule.Name)
+				return c.Next()
+			}
+			setLimitHeaders(c, rule.Max, hits, resetAt)
+			if hits > rule.Max {
+				return tooManyRequests(c, resetAt, "Too many requests")
+			}
+		}
+		if rule.Bandwidth <= 0 {
+			return c.Next()
+		}
+
+		bytesKey := key + ":bytes"
+		received := int64(len(c.Request().Body()))
+		used, err := l.store.Increment(ctx, bytesKey, received, resetAt)
+		if err != nil {
+			l.log.Errorw("unable to count bandwidth for rate limit", "error", err, "rule", rule.Name)
+			return c.Next()
+		}
+		if used > rule.Bandwidth {
+			// The rejected request does not use the bandwidth
+			if received > 0 {
+				if _, err := l.store.Increment(ctx, bytesKey, -received, resetAt); err != nil {
+					l.log.Errorw("unable to refund bandwidth for rate limit", "error", err, "rule", rule.Name)
+				}
+			}
+			return tooManyRequests(c, resetAt, "Bandwidth limit exceeded")
+		}
+
+		err = c.Next()
+		if sent := responseLength(c); sent > 0 && c.Method() != fiber.MethodHead {
+			if _, err := l.store.Increment(ctx, bytesKey, sent, resetAt); err != nil {
+				l.log.Errorw("unable to count bandwidth for rate limit", "error", err, "rule", rule.Name)
+			}
+		}
+		return err
+	}
+}
+
+// responseLength is the length of the response body, or -1 if the streamed body has no length
+func responseLength(c *fiber.Ctx) int64 {
+	if c.Response().IsBodyStream() {
+		return int64(c.Response().Header.ContentLength())
+	}
+	return int64(len(c.Response().Body()))
+}
+
+func setLimitHeaders(c *fiber.Ctx, max int64, hits int64, resetAt time.Time) {
+	remaining := max - hits
+	if remaining < 0 {
+		remaining = 0
+	}
+	c.Set(HeaderLimit, strconv.FormatInt(max, 10))
+	c.Set(HeaderRemaining, strconv.FormatInt(remaining, 10))
+	c.Set(HeaderReset, strconv.FormatInt(secondsUntil(resetAt), 10))
+}
+
+func tooManyRequests(c *fiber.Ctx, resetAt time.Time, message string) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(secondsUntil(resetAt), 10))
+	return fiber.NewError(fiber.StatusTooManyRequests, message)
+}
+
+// secondsUntil rounds up, so the client does not retry before the window ends
+func secondsUntil(t time.Time) int64 {
+	return int64((time.Until(t) + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"time"
+)
+
+const (
+	StoreMemory   = "memory"
+	StoreDatabase = "database"
+	StoreRedis    = "redis"
+)
+
+// Store counts the hits of the keys. The limits are shared by the replicas when the store is shared.
+type Store interface {
+	// Increment adds n to the hits of the key and returns the new hits.
+	// The key already contains the start of the window, so the hits can be dropped after expiredAt.
+	Increment(ctx context.Context, key string, n int64, expiredAt time.Time) (int64, error)
+}
+
+// NewStore creates the store by type, the database store counts in the rate limit table of dataStore
+func NewStore(storeType string, dataStore data.RateLimitDataStore, redisURL string) (Store, error) {
+	switch storeType {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreDatabase:
+		return dataStore, nil
+	case StoreRedis:
+		return NewRedisStore(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %s", storeType)
+	}
+}
+
+// KeyGenerator identifies the client of the request, the clients with the same key share the limits
+type KeyGenerator func(c *fiber.Ctx) string
+
+// IPKey identifies the client by IP, it does not need the user of the request
+func IPKey(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// Rule limits the requests and the transferred bytes of each client in every window.
+// The limit is disabled if it is zero.
+type Rule struct {
+	// Name separates the counters of the rules, so the same client has a bucket for each rule
+	Name      string
+	Max       int64
+	Bandwidth int64
+	Window    time.Duration
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the minimum interval between the removals of the expired counters
+const sweepInterval = time.Minute
+
+// MemoryStore counts the hits in the process, each replica has its own limits
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+}
+
+type memoryCounter struct {
+	hits      int64
+	expiredAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters:  make(map[string]memoryCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, n int64, expiredAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, counter := range s.counters {
+			if !now.Before(counter.expiredAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	counter := s.counters[key]
+	counter.hits += n
+	counter.expiredAt = expiredAt
+	s.counters[key] = counter
+	return counter.hits, nil
+}
@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingStore struct{}
+
+func (failingStore) Increment(context.Context, string, int64, time.Time) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func newTestApp(store Store, rule Rule) *fiber.App {
+	app := fiber.New()
+	limiter := New(zap.NewNop().Sugar(), store, func(c *fiber.Ctx) string {
+		return c.Get("X-Client")
+	})
+	app.Post("/", limiter.Handler(rule), func(c *fiber.Ctx) error {
+		return c.SendString(c.Query("response"))
+	})
+	return app
+}
+
+func testRequest(t *testing.T, app *fiber.App, client string, body string, response string) int {
+	req := httptest.NewRequest(fiber.MethodPost, "/?response="+response, strings.NewReader(body))
+	req.Header.Set("X-Client", client)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestLimiterMax(t *testing.T) {
+	app := newTestApp(NewMemoryStore(), Rule{Name: "test", Max: 2, Window: time.Hour})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+	req.Header.Set("X-Client", "a")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Equal(t, "2", resp.Header.Get(HeaderLimit))
+	require.Equal(t, "1", resp.Header.Get(HeaderRemaining))
+	require.NotEmpty(t, resp.Header.Get(HeaderReset))
+
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "a", "", ""))
+	require.Equal(t, fiber.StatusTooManyRequests, testRequest(t, app, "a", "", ""))
+	// The other client has its own bucket
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "b", "", ""))
+}
+
+func TestLimiterBandwidth(t *testing.T) {
+	app := newTestApp(NewMemoryStore(), Rule{Name: "test", Bandwidth: 10, Window: time.Hour})
+
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "a", "12345", ""))
+	// The rejected request body is not counted
+	require.Equal(t, fiber.StatusTooManyRequests, testRequest(t, app, "a", "123456", ""))
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "a", "", "12345"))
+	require.Equal(t, fiber.StatusTooManyRequests, testRequest(t, app, "a", "1", ""))
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "b", "1234567890", ""))
+}
+
+func TestLimiterStoreError(t *testing.T) {
+	app := newTestApp(failingStore{}, Rule{Name: "test", Max: 1, Bandwidth: 1, Window: time.Hour})
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "a", "12345", ""))
+	require.Equal(t, fiber.StatusOK, testRequest(t, app, "a", "12345", ""))
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	app := newTestApp(failingStore{}, Rule{Name: "test", Window: time.Hour})
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.Empty(t, resp.Header.Get(HeaderLimit))
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	expiredAt := time.Now().Add(time.Hour)
+	hits, err := store.Increment(context.Background(), "key", 2, expiredAt)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), hits)
+	hits, err = store.Increment(context.Background(), "key", 3, expiredAt)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), hits)
+
+	// The expired counters are removed by the sweep
+	_, err = store.Increment(context.Background(), "expired", 1, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	store.lastSweep = time.Now().Add(-sweepInterval)
+	_, err = store.Increment(context.Background(), "key", 0, expiredAt)
+	require.NoError(t, err)
+	require.NotContains(t, store.counters, "expired")
+	require.Contains(t, store.counters, "key")
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisStore("redis://" + server.Addr())
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Ping(context.Background()))
+
+	expiredAt := time.Now().Add(time.Minute)
+	hits, err := store.Increment(context.Background(), "key", 2, expiredAt)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), hits)
+	hits, err = store.Increment(context.Background(), "key", 3, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), hits)
+	// The expiry is kept from the first increment
+	ttl := server.TTL(keyPrefix + "key")
+	require.True(t, ttl > 0 && ttl <= time.Minute, ttl)
+
+	server.FastForward(time.Minute)
+	hits, err = store.Increment(context.Background(), "key", 1, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), hits)
+
+	_, err = NewRedisStore("localhost:6379")
+	require.Error(t, err)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(StoreMemory, nil, "")
+	require.NoError(t, err)
+	require.IsType(t, &MemoryStore{}, store)
+
+	server := miniredis.RunT(t)
+	store, err = NewStore(StoreRedis, nil, "redis://"+server.Addr())
+	require.NoError(t, err)
+	require.IsType(t, &RedisStore{}, store)
+
+	_, err = NewStore("memcached", nil, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// keyPrefix separates the counters from the other data in the same Redis database
+const keyPrefix = "ratelimit:"
+
+// incrementScript sets the expiry only once, so the incremented counter keeps the end of its window
+var incrementScript = redis.NewScript(`
+local hits = redis.call("INCRBY", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return hits
+`)
+
+// RedisStore counts the hits in Redis or the server compatible with its protocol, the limits are shared by the replicas
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the server by URL, e.g. redis://:password@localhost:6379/0
+func NewRedisStore(url string) (*RedisStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{
+		client: redis.NewClient(options),
+	}, nil
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, n int64, expiredAt time.Time) (int64, error) {
+	ttl := time.Until(expiredAt).Milliseconds()
+	if ttl < 1 {
+		ttl = 1
+	}
+	return incrementScript.Run(ctx, s.client, []string{keyPrefix + key}, n, ttl).Int64()
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
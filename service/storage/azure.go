@@ -25,9 +25,9 @@ func NewAzureImageStorageManager(l *zap.SugaredLogger, connectionString string,
 	}, nil
 }
 
-func (a *AzureImageStorageManager) UploadImage(fileName, mimeType string, file io.ReadSeekCloser) error {
+func (a *AzureImageStorageManager) UploadImage(ctx context.Context, fileName, mimeType string, file io.ReadSeekCloser) error {
 	bbClient := a.containerClient.NewBlockBlobClient(fileName)
-	_, err := bbClient.Upload(context.Background(), file, &azblob.UploadBlockBlobOptions{
+	_, err := bbClient.Upload(ctx, file, &azblob.UploadBlockBlobOptions{
 		HTTPHeaders: &azblob.BlobHTTPHeaders{ BlobContentType: &mimeType },
 	})
 	if err != nil {
@@ -36,11 +36,17 @@ func (a *AzureImageStorageManager) UploadImage(fileName, mimeType string, file i
 	return err
 }
 
-func (a *AzureImageStorageManager) DeleteImage(fileName string) error {
+func (a *AzureImageStorageManager) DeleteImage(ctx context.Context, fileName string) error {
 	bbClient := a.containerClient.NewBlockBlobClient(fileName)
-	_, err := bbClient.Delete(context.Background(), nil)
+	_, err := bbClient.Delete(ctx, nil)
 	if err != nil {
 		a.log.Errorw("Failed to delete file on az blob", "error", err)
 	}
 	return err
 }
+
+// Ping checks that the container is reachable with the credentials
+func (a *AzureImageStorageManager) Ping(ctx context.Context) error {
+	_, err := a.containerClient.GetProperties(ctx, nil)
+	return err
+}
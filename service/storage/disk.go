@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"go.uber.org/zap"
 	"io"
 	"os"
+	"strings"
 )
 
 type DiskStorageManager struct {
@@ -33,7 +35,7 @@ func (m *DiskStorageManager) getFilePath(fileName string) string {
 	return fmt.Sprintf("%s/%s", m.path, fileName)
 }
 
-func (m *DiskStorageManager) OpenFile(fileName string) (io.Reader, error) {
+func (m *DiskStorageManager) OpenFile(ctx context.Context, fileName string) (io.Reader, error) {
 	file, err := os.Open(m.getFilePath(fileName))
 	if err != nil {
 		m.log.Errorw("cannot open file on disk", "error", err)
@@ -42,32 +44,57 @@ func (m *DiskStorageManager) OpenFile(fileName string) (io.Reader, error) {
 	return file, nil
 }
 
-func (m *DiskStorageManager) WriteToNewFile(fileName string, reader io.Reader) error {
-	file, err := os.Create(m.getFilePath(fileName))
+// WriteToNewFile writes the content to the file.
+// The partially written file is removed if the write fails or the context is cancelled.
+func (m *DiskStorageManager) WriteToNewFile(ctx context.Context, fileName string, reader io.Reader) error {
+	filePath := m.getFilePath(fileName)
+	file, err := os.Create(filePath)
 	if err != nil {
 		m.log.Errorw("cannot create new file on disk", "error", err)
 		return err
 	}
 
-	if _, err = io.Copy(file, reader); err != nil {
+	if err := writeAndClose(ctx, file, reader); err != nil {
 		m.log.Errorw("unable to write data to file", "error", err)
+		if err := os.Remove(filePath); err != nil {
+			m.log.Errorw("unable to remove partially written file", "error", err)
+		}
 		return err
 	}
 
-	// Clean up the file
-	if err := file.Sync(); err != nil {
-		m.log.Errorw("unable sync the file to disk", "error", err)
+	return nil
+}
+
+func writeAndClose(ctx context.Context, file *os.File, reader io.Reader) error {
+	if _, err := io.Copy(file, &contextReader{ctx: ctx, reader: reader}); err != nil {
+		_ = file.Close()
 		return err
 	}
-	if err := file.Close(); err != nil {
-		m.log.Errorw("unable close the file", "error", err)
+
+	// Clean up the file
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
 		return err
 	}
+	return file.Close()
+}
 
-	return nil
+// contextReader stops reading when the context is cancelled.
+// The upload body is received by fasthttp before the handler, so a client that disconnects mid-upload never reaches the write,
+// the write of the received body is only stopped by the storage timeout.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
 }
 
-func (m *DiskStorageManager) Exist(fileName string) (bool, error) {
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+func (m *DiskStorageManager) Exist(ctx context.Context, fileName string) (bool, error) {
 	if _, err := os.Stat(m.getFilePath(fileName)); err != nil {
 		if os.IsNotExist(err) {
 			return false, nil
@@ -78,7 +105,7 @@ func (m *DiskStorageManager) Exist(fileName string) (bool, error) {
 	return true, nil
 }
 
-func (m *DiskStorageManager) ListFiles() ([]string, error) {
+func (m *DiskStorageManager) ListFiles(ctx context.Context) ([]string, error) {
 	dir, err := os.Open(m.path)
 	if err != nil {
 		m.log.Errorw("unable to open storage directory", "error", err)
@@ -92,13 +119,30 @@ func (m *DiskStorageManager) ListFiles() ([]string, error) {
 		return nil, err
 	}
 
-	return files, nil
+	// Hidden files are temporary files that are still being written
+	fileNames := make([]string, 0, len(files))
+	for _, fileName := range files {
+		if !strings.HasPrefix(fileName, ".") {
+			fileNames = append(fileNames, fileName)
+		}
+	}
+
+	return fileNames, nil
 }
 
-func (m *DiskStorageManager) DeleteFile(fileName string) error {
+func (m *DiskStorageManager) DeleteFile(ctx context.Context, fileName string) error {
 	if err := os.Remove(m.getFilePath(fileName)); err != nil {
 		m.log.Errorw(fmt.Sprintf("unable to delete file %s", fileName), "error", err)
 		return err
 	}
 	return nil
 }
+
+// RenameFile moves the file to the new name, replacing the existing file atomically
+func (m *DiskStorageManager) RenameFile(ctx context.Context, oldName string, newName string) error {
+	if err := os.Rename(m.getFilePath(oldName), m.getFilePath(newName)); err != nil {
+		m.log.Errorw(fmt.Sprintf("unable to rename file %s to %s", oldName, newName), "error", err)
+		return err
+	}
+	return nil
+}
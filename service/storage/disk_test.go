@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 const StoragePath = "files"
@@ -45,7 +47,7 @@ func TestWriteToNewFile(t *testing.T) {
 	fileName := "test-write"
 	fileContent := "hello world. This is some string to be tested"
 	fileInputReader := strings.NewReader(fileContent)
-	err = diskStorageManager.WriteToNewFile(fileName, fileInputReader)
+	err = diskStorageManager.WriteToNewFile(context.Background(), fileName, fileInputReader)
 	require.NoError(t, err)
 
 	// Check file on disk
@@ -57,6 +59,40 @@ func TestWriteToNewFile(t *testing.T) {
 	require.Equal(t, fileContent, diskFileString.String())
 }
 
+// cancelReader cancels the context after the first read, like a timeout that expires mid-write
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return copy(p, "partial content"), nil
+}
+
+func TestWriteToNewFileCancelled(t *testing.T) {
+	diskStorageManager, err := createDiskStorageManager()
+	require.NoError(t, err)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fileName := "test-write-cancelled"
+	err = diskStorageManager.WriteToNewFile(ctx, fileName, &cancelReader{cancel: cancel})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoFileExists(t, fmt.Sprintf("%s/%s", StoragePath, fileName))
+}
+
+func TestTimeoutFileManager(t *testing.T) {
+	diskStorageManager, err := createDiskStorageManager()
+	require.NoError(t, err)
+	defer cleanup()
+
+	manager := NewTimeoutFileManager(diskStorageManager, time.Nanosecond)
+	fileName := "test-write-timeout"
+	err = manager.WriteToNewFile(context.Background(), fileName, strings.NewReader("hello world"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoFileExists(t, fmt.Sprintf("%s/%s", StoragePath, fileName))
+}
+
 func TestOpenFile(t *testing.T) {
 	diskStorageManager, err := createDiskStorageManager()
 	require.NoError(t, err)
@@ -67,7 +103,7 @@ func TestOpenFile(t *testing.T) {
 	err = createTestFile(fileName, fileContent)
 	require.NoError(t, err)
 
-	fileReader, err := diskStorageManager.OpenFile(fileName)
+	fileReader, err := diskStorageManager.OpenFile(context.Background(), fileName)
 	require.NoError(t, err)
 
 	// Checking
@@ -82,7 +118,7 @@ func TestOpenDeletedFile(t *testing.T) {
 	require.NoError(t, err)
 	defer cleanup()
 
-	_, err = diskStorageManager.OpenFile("doesNotExist")
+	_, err = diskStorageManager.OpenFile(context.Background(), "doesNotExist")
 	require.Error(t, err)
 }
 
@@ -96,7 +132,7 @@ func TestFileExist(t *testing.T) {
 	err = createTestFile(fileName, fileContent)
 	require.NoError(t, err)
 
-	isExist, err := diskStorageManager.Exist(fileName)
+	isExist, err := diskStorageManager.Exist(context.Background(), fileName)
 	require.NoError(t, err)
 	require.True(t, isExist)
 }
@@ -106,7 +142,7 @@ func TestFileNotExist(t *testing.T) {
 	require.NoError(t, err)
 	defer cleanup()
 
-	isExist, err := diskStorageManager.Exist("doesNotExist")
+	isExist, err := diskStorageManager.Exist(context.Background(), "doesNotExist")
 	require.NoError(t, err)
 	require.False(t, isExist)
 }
@@ -123,7 +159,10 @@ func TestListFiles(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	fileLists, err := diskStorageManager.ListFiles()
+	// Temporary files should not be listed
+	require.NoError(t, createTestFile(".test-list-tmp", fileContent))
+
+	fileLists, err := diskStorageManager.ListFiles(context.Background())
 	require.NoError(t, err)
 	require.Len(t, fileLists, len(fileNameLists))
 	for file := range fileLists {
@@ -147,7 +186,25 @@ func TestDeleteFile(t *testing.T) {
 	err = createTestFile(fileName, fileContent)
 	require.NoError(t, err)
 
-	err = diskStorageManager.DeleteFile(fileName)
+	err = diskStorageManager.DeleteFile(context.Background(), fileName)
 	require.NoError(t, err)
 	require.NoFileExists(t, fmt.Sprintf("%s/%s", StoragePath, fileName))
 }
+
+func TestRenameFile(t *testing.T) {
+	diskStorageManager, err := createDiskStorageManager()
+	require.NoError(t, err)
+	defer cleanup()
+
+	fileContent := "When I was a young boy, my father took me into the city to see a marching band"
+	require.NoError(t, createTestFile("test-rename-old", fileContent))
+	require.NoError(t, createTestFile("test-rename-new", "old content"))
+
+	err = diskStorageManager.RenameFile(context.Background(), "test-rename-old", "test-rename-new")
+	require.NoError(t, err)
+	require.NoFileExists(t, fmt.Sprintf("%s/%s", StoragePath, "test-rename-old"))
+
+	content, err := os.ReadFile(fmt.Sprintf("%s/%s", StoragePath, "test-rename-new"))
+	require.NoError(t, err)
+	require.Equal(t, fileContent, string(content))
+}
@@ -1,16 +1,20 @@
 package storage
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 type FileManager interface {
-	OpenFile(fileName string) (io.Reader, error)
-	WriteToNewFile(fileName string, reader io.Reader) error
-	Exist(fileName string) (bool, error)
-	ListFiles() ([]string, error)
-	DeleteFile(fileName string) error
+	OpenFile(ctx context.Context, fileName string) (io.Reader, error)
+	WriteToNewFile(ctx context.Context, fileName string, reader io.Reader) error
+	Exist(ctx context.Context, fileName string) (bool, error)
+	ListFiles(ctx context.Context) ([]string, error)
+	DeleteFile(ctx context.Context, fileName string) error
+	RenameFile(ctx context.Context, oldName string, newName string) error
 }
 
 type ImageManager interface {
-	UploadImage(fileName string, mimeType string,file io.ReadSeekCloser) error
-	DeleteImage(fileName string) error
+	UploadImage(ctx context.Context, fileName string, mimeType string,file io.ReadSeekCloser) error
+	DeleteImage(ctx context.Context, fileName string) error
 }
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// TimeoutFileManager cancels the operations of the wrapped FileManager that take longer than the timeout.
+// OpenFile only limits opening the file, the content is read later by the caller.
+type TimeoutFileManager struct {
+	manager FileManager
+	timeout time.Duration
+}
+
+func NewTimeoutFileManager(manager FileManager, timeout time.Duration) *TimeoutFileManager {
+	return &TimeoutFileManager{manager: manager, timeout: timeout}
+}
+
+func (m *TimeoutFileManager) OpenFile(ctx context.Context, fileName string) (io.Reader, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.OpenFile(ctx, fileName)
+}
+
+func (m *TimeoutFileManager) WriteToNewFile(ctx context.Context, fileName string, reader io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.WriteToNewFile(ctx, fileName, reader)
+}
+
+func (m *TimeoutFileManager) Exist(ctx context.Context, fileName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.Exist(ctx, fileName)
+}
+
+func (m *TimeoutFileManager) ListFiles(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.ListFiles(ctx)
+}
+
+func (m *TimeoutFileManager) DeleteFile(ctx context.Context, fileName string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.DeleteFile(ctx, fileName)
+}
+
+func (m *TimeoutFileManager) RenameFile(ctx context.Context, oldName string, newName string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.RenameFile(ctx, oldName, newName)
+}
+
+// TimeoutImageManager cancels the operations of the wrapped ImageManager that take longer than the timeout
+type TimeoutImageManager struct {
+	manager ImageManager
+	timeout time.Duration
+}
+
+func NewTimeoutImageManager(manager ImageManager, timeout time.Duration) *TimeoutImageManager {
+	return &TimeoutImageManager{manager: manager, timeout: timeout}
+}
+
+func (m *TimeoutImageManager) UploadImage(ctx context.Context, fileName string, mimeType string, file io.ReadSeekCloser) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.UploadImage(ctx, fileName, mimeType, file)
+}
+
+func (m *TimeoutImageManager) DeleteImage(ctx context.Context, fileName string) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.manager.DeleteImage(ctx, fileName)
+}
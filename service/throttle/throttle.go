@@ -0,0 +1,152 @@
+package throttle
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"golang.org/x/time/rate"
+	"io"
+)
+
+// minBurst keeps the reads large enough for the low limits, so the stream is not split into tiny chunks
+const minBurst = 32 << 10
+
+// Limits is the throughput cap of each connection in bytes per second, zero disables the cap
+type Limits struct {
+	Anonymous     int64
+	Authenticated int64
+	Admin         int64
+}
+
+// For is the cap of the connection of the user, the user is nil for the anonymous client
+func (l Limits) For(user *model.User) int64 {
+	switch {
+	case user == nil:
+		return l.Anonymous
+	case user.Role == model.RoleAdmin:
+		return l.Admin
+	default:
+		return l.Authenticated
+	}
+}
+
+// Throttle caps the throughput of each connection and of all the connections together
+type Throttle struct {
+	global *rate.Limiter
+	limits Limits
+}
+
+// New creates the throttle with the global cap in bytes per second, zero disables the cap
+func New(global int64, limits Limits) *Throttle {
+	t := &Throttle{limits: limits}
+	if global > 0 {
+		t.global = newLimiter(global)
+	}
+	return t
+}
+
+// Reader caps the throughput of reading r by the connection cap of the user and the global cap.
+// The waiting read returns the error of ctx when it is done.
+func (t *Throttle) Reader(ctx context.Context, r io.ReadCloser, user *model.User) io.ReadCloser {
+	limiters, maxRead := t.limiters(user)
+	if len(limiters) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, reader: r, limiters: limiters, maxRead: maxRead}
+}
+
+// Writer caps the throughput of writing to w like Reader, for the content that is generated while it is sent
+func (t *Throttle) Writer(ctx context.Context, w io.Writer, user *model.User) io.Writer {
+	limiters, maxWrite := t.limiters(user)
+	if len(limiters) == 0 {
+		return w
+	}
+	return &writer{ctx: ctx, writer: w, limiters: limiters, maxWrite: maxWrite}
+}
+
+// limiters returns the limiters of the connection of the user and their smallest burst
+func (t *Throttle) limiters(user *model.User) ([]*rate.Limiter, int) {
+	var limiters []*rate.Limiter
+	if t.global != nil {
+		limiters = append(limiters, t.global)
+	}
+	if limit := t.limits.For(user); limit > 0 {
+		limiters = append(limiters, newLimiter(limit))
+	}
+	if len(limiters) == 0 {
+		return nil, 0
+	}
+
+	minBurst := limiters[0].Burst()
+	for _, limiter := range limiters[1:] {
+		if limiter.Burst() < minBurst {
+			minBurst = limiter.Burst()
+		}
+	}
+	return limiters, minBurst
+}
+
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := int(bytesPerSecond)
+	if burst < minBurst {
+		burst = minBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+type reader struct {
+	ctx      context.Context
+	reader   io.ReadCloser
+	limiters []*rate.Limiter
+	// maxRead is the smallest burst, WaitN fails if n is larger than the burst
+	maxRead int
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > r.maxRead {
+		p = p[:r.maxRead]
+	}
+	n, err := r.reader.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+	for _, limiter := range r.limiters {
+		if waitErr := limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *reader) Close() error {
+	return r.reader.Close()
+}
+
+type writer struct {
+	ctx      context.Context
+	writer   io.Writer
+	limiters []*rate.Limiter
+	// maxWrite is the smallest burst, the larger write is split
+	maxWrite int
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > w.maxWrite {
+			chunk = chunk[:w.maxWrite]
+		}
+		for _, limiter := range w.limiters {
+			if err := limiter.WaitN(w.ctx, len(chunk)); err != nil {
+				return written, err
+			}
+		}
+		n, err := w.writer.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
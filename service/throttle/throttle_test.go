@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"io"
+	"testing"
+	"time"
+)
+
+func newTestReader(size int) io.ReadCloser {
+	return io.NopCloser(bytes.NewReader(make([]byte, size)))
+}
+
+func TestLimitsFor(t *testing.T) {
+	limits := Limits{Anonymous: 1, Authenticated: 2, Admin: 3}
+	require.Equal(t, int64(1), limits.For(nil))
+	require.Equal(t, int64(2), limits.For(&model.User{Role: model.RoleUser}))
+	require.Equal(t, int64(3), limits.For(&model.User{Role: model.RoleAdmin}))
+}
+
+func TestThrottleDisabled(t *testing.T) {
+	r := newTestReader(10)
+	throttle := New(0, Limits{Anonymous: 1})
+	require.Equal(t, r, throttle.Reader(context.Background(), r, &model.User{}))
+}
+
+func TestThrottleConnection(t *testing.T) {
+	throttle := New(0, Limits{Anonymous: minBurst})
+
+	// The first burst is read immediately, the rest waits for the tokens
+	start := time.Now()
+	n, err := io.Copy(io.Discard, throttle.Reader(context.Background(), newTestReader(minBurst+minBurst/2), nil))
+	require.NoError(t, err)
+	require.Equal(t, int64(minBurst+minBurst/2), n)
+	require.True(t, time.Since(start) >= 400*time.Millisecond, time.Since(start))
+
+	// Each connection has its own cap
+	start = time.Now()
+	_, err = io.Copy(io.Discard, throttle.Reader(context.Background(), newTestReader(minBurst), nil))
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < 400*time.Millisecond, time.Since(start))
+}
+
+func TestThrottleGlobal(t *testing.T) {
+	throttle := New(minBurst, Limits{})
+
+	_, err := io.Copy(io.Discard, throttle.Reader(context.Background(), newTestReader(minBurst), nil))
+	require.NoError(t, err)
+	// The other connection waits for the tokens used by the first one
+	start := time.Now()
+	_, err = io.Copy(io.Discard, throttle.Reader(context.Background(), newTestReader(minBurst/2), &model.User{Role: model.RoleAdmin}))
+	require.NoError(t, err)
+	require.True(t, time.Since(start) >= 400*time.Millisecond, time.Since(start))
+}
+
+func TestThrottleWriter(t *testing.T) {
+	throttle := New(0, Limits{Anonymous: minBurst})
+
+	// The large write is split into the bursts
+	var buf bytes.Buffer
+	start := time.Now()
+	n, err := throttle.Writer(context.Background(), &buf, nil).Write(make([]byte, minBurst+minBurst/2))
+	require.NoError(t, err)
+	require.Equal(t, minBurst+minBurst/2, n)
+	require.Equal(t, minBurst+minBurst/2, buf.Len())
+	require.True(t, time.Since(start) >= 400*time.Millisecond, time.Since(start))
+
+	// The writer is not wrapped without the cap
+	require.Equal(t, io.Writer(&buf), New(0, Limits{}).Writer(context.Background(), &buf, nil))
+}
+
+func TestThrottleCanceled(t *testing.T) {
+	throttle := New(0, Limits{Authenticated: minBurst})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := io.Copy(io.Discard, throttle.Reader(ctx, newTestReader(minBurst*2), &model.User{Role: model.RoleUser}))
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -5,4 +5,5 @@ type Manager interface {
 	GenerateFileID() (string, error)
 	GenerateImageToken() (string, error)
 	GenerateAPIToken() (string, error)
+	GenerateWebhookSecret() (string, error)
 }
@@ -25,3 +25,7 @@ func (n *NanoIDTokenManager) GenerateImageToken() (string, error) {
 func (n *NanoIDTokenManager) GenerateAPIToken() (string, error) {
 	return gonanoid.New(30)
 }
+
+func (n *NanoIDTokenManager) GenerateWebhookSecret() (string, error) {
+	return gonanoid.New(40)
+}
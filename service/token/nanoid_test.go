@@ -32,3 +32,10 @@ func TestGenerateAPIToken(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, len(token), 30)
 }
+
+func TestGenerateWebhookSecret(t *testing.T) {
+	nanoIDManager := NewNanoIDTokenManager()
+	token, err := nanoIDManager.GenerateWebhookSecret()
+	require.NoError(t, err)
+	require.Equal(t, len(token), 40)
+}
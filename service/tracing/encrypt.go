@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/service/encrypt"
+	"go.opentelemetry.io/otel/attribute"
+	"io"
+)
+
+// EncryptionManager creates a span for every operation of the wrapped encrypt.Manager.
+// The spans cover getting the data key, which may call the key provider.
+// The content is encrypted while it is streamed, so that time is part of the storage spans.
+type EncryptionManager struct {
+	manager encrypt.Manager
+}
+
+func NewEncryptionManager(manager encrypt.Manager) *EncryptionManager {
+	return &EncryptionManager{manager: manager}
+}
+
+func (m *EncryptionManager) Encrypt(ctx context.Context, input io.Reader) (io.Reader, *encrypt.Metadata, error) {
+	ctx, span := tracer().Start(ctx, "encrypt.encrypt")
+	output, metadata, err := m.manager.Encrypt(ctx, input)
+	if metadata != nil {
+		span.SetAttributes(attribute.String("encrypt.key_id", metadata.KeyID))
+	}
+	end(span, err)
+	return output, metadata, err
+}
+
+func (m *EncryptionManager) Decrypt(ctx context.Context, input io.Reader, metadata *encrypt.Metadata) (io.Reader, error) {
+	ctx, span := tracer().Start(ctx, "encrypt.decrypt")
+	span.SetAttributes(attribute.String("encrypt.key_id", metadata.KeyID), attribute.Bool("encrypt.envelope", len(metadata.WrappedKey) > 0))
+	output, err := m.manager.Decrypt(ctx, input, metadata)
+	end(span, err)
+	return output, err
+}
+
+func (m *EncryptionManager) CurrentKeyID() string {
+	return m.manager.CurrentKeyID()
+}
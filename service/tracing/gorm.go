@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"errors"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin creates a span for every query run by gorm.
+// The span is the child of the span in the context given to db.WithContext.
+type GormPlugin struct{}
+
+func (p *GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	registers := []func() error{
+		func() error {
+			return callback.Create().Before("gorm:create").Register("tracing:before_create", before("create"))
+		},
+		func() error { return callback.Create().After("gorm:create").Register("tracing:after_create", after) },
+		func() error {
+			return callback.Query().Before("gorm:query").Register("tracing:before_query", before("query"))
+		},
+		func() error { return callback.Query().After("gorm:query").Register("tracing:after_query", after) },
+		func() error {
+			return callback.Update().Before("gorm:update").Register("tracing:before_update", before("update"))
+		},
+		func() error { return callback.Update().After("gorm:update").Register("tracing:after_update", after) },
+		func() error {
+			return callback.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete"))
+		},
+		func() error { return callback.Delete().After("gorm:delete").Register("tracing:after_delete", after) },
+		func() error { return callback.Row().Before("gorm:row").Register("tracing:before_row", before("row")) },
+		func() error { return callback.Row().After("gorm:row").Register("tracing:after_row", after) },
+		func() error { return callback.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")) },
+		func() error { return callback.Raw().After("gorm:raw").Register("tracing:after_raw", after) },
+	}
+	for _, register := range registers {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := tracer().Start(db.Statement.Context, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemKey.String(db.Dialector.Name()),
+			semconv.DBOperationKey.String(operation),
+		))
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBSQLTableKey.String(db.Statement.Table),
+		semconv.DBStatementKey.String(db.Statement.SQL.String()),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	end(span, err)
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+)
+
+// FileManager creates a span for every operation of the wrapped storage.FileManager
+type FileManager struct {
+	backend string
+	manager storage.FileManager
+}
+
+func NewFileManager(backend string, manager storage.FileManager) *FileManager {
+	return &FileManager{backend: backend, manager: manager}
+}
+
+func (m *FileManager) start(ctx context.Context, operation string, fileName string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "storage."+operation, trace.WithAttributes(
+		attribute.String("storage.backend", m.backend),
+		attribute.String("storage.file", fileName),
+	))
+}
+
+// OpenFile only covers opening the file, the content is read later by the caller
+func (m *FileManager) OpenFile(ctx context.Context, fileName string) (io.Reader, error) {
+	ctx, span := m.start(ctx, "open", fileName)
+	file, err := m.manager.OpenFile(ctx, fileName)
+	end(span, err)
+	return file, err
+}
+
+// WriteToNewFile covers the whole write, including reading and encrypting the content from the reader
+func (m *FileManager) WriteToNewFile(ctx context.Context, fileName string, reader io.Reader) error {
+	ctx, span := m.start(ctx, "write", fileName)
+	err := m.manager.WriteToNewFile(ctx, fileName, reader)
+	end(span, err)
+	return err
+}
+
+func (m *FileManager) Exist(ctx context.Context, fileName string) (bool, error) {
+	ctx, span := m.start(ctx, "exist", fileName)
+	exist, err := m.manager.Exist(ctx, fileName)
+	end(span, err)
+	return exist, err
+}
+
+func (m *FileManager) ListFiles(ctx context.Context) ([]string, error) {
+	ctx, span := m.start(ctx, "list", "")
+	files, err := m.manager.ListFiles(ctx)
+	end(span, err)
+	return files, err
+}
+
+func (m *FileManager) DeleteFile(ctx context.Context, fileName string) error {
+	ctx, span := m.start(ctx, "delete", fileName)
+	err := m.manager.DeleteFile(ctx, fileName)
+	end(span, err)
+	return err
+}
+
+func (m *FileManager) RenameFile(ctx context.Context, oldName string, newName string) error {
+	ctx, span := m.start(ctx, "rename", oldName)
+	span.SetAttributes(attribute.String("storage.new_file", newName))
+	err := m.manager.RenameFile(ctx, oldName, newName)
+	end(span, err)
+	return err
+}
+
+// ImageManager creates a span for every operation of the wrapped storage.ImageManager
+type ImageManager struct {
+	backend string
+	manager storage.ImageManager
+}
+
+func NewImageManager(backend string, manager storage.ImageManager) *ImageManager {
+	return &ImageManager{backend: backend, manager: manager}
+}
+
+func (m *ImageManager) start(ctx context.Context, operation string, fileName string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "storage."+operation, trace.WithAttributes(
+		attribute.String("storage.backend", m.backend),
+		attribute.String("storage.file", fileName),
+	))
+}
+
+func (m *ImageManager) UploadImage(ctx context.Context, fileName string, mimeType string, file io.ReadSeekCloser) error {
+	ctx, span := m.start(ctx, "upload_image", fileName)
+	err := m.manager.UploadImage(ctx, fileName, mimeType, file)
+	end(span, err)
+	return err
+}
+
+func (m *ImageManager) DeleteImage(ctx context.Context, fileName string) error {
+	ctx, span := m.start(ctx, "delete_image", fileName)
+	err := m.manager.DeleteImage(ctx, fileName)
+	end(span, err)
+	return err
+}
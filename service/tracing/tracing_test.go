@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/service/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func newRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func findSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "span %s is not ended", name)
+	return nil
+}
+
+type testRecord struct {
+	ID   uint
+	Name string
+}
+
+type failingFileManager struct {
+	storage.FileManager
+}
+
+func (m *failingFileManager) DeleteFile(context.Context, string) error {
+	return errors.New("unable to delete")
+}
+
+func TestFiberMiddleware(t *testing.T) {
+	recorder := newRecorder()
+	_, err := Init(context.Background(), Config{})
+	require.NoError(t, err)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(&GormPlugin{}))
+	require.NoError(t, db.AutoMigrate(&testRecord{}))
+	manager := NewFileManager("test", &failingFileManager{})
+
+	app := fiber.New()
+	app.Use(NewFiberMiddleware())
+	app.Get("/records/:id", func(c *fiber.Ctx) error {
+		var records []testRecord
+		if err := db.WithContext(c.UserContext()).Find(&records).Error; err != nil {
+			return err
+		}
+		if err := manager.DeleteFile(c.UserContext(), c.Params("id")); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Unable to delete")
+		}
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/records/1", nil)
+	// The span continues the trace of the client
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	res, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusInternalServerError, res.StatusCode)
+
+	requestSpan := findSpan(t, recorder, "GET /records/:id")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", requestSpan.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", requestSpan.Parent().SpanID().String())
+	require.Equal(t, codes.Error, requestSpan.Status().Code)
+
+	querySpan := findSpan(t, recorder, "gorm.query")
+	require.Equal(t, requestSpan.SpanContext().SpanID(), querySpan.Parent().SpanID())
+	require.Equal(t, codes.Unset, querySpan.Status().Code)
+
+	storageSpan := findSpan(t, recorder, "storage.delete")
+	require.Equal(t, requestSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+	require.Equal(t, codes.Error, storageSpan.Status().Code)
+}
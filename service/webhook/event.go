@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"github.com/matoous/go-nanoid/v2"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"time"
+)
+
+// ValidEventType reports whether the event type can be subscribed
+func ValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEvent creates the event with the unique ID, the receiver can use it to ignore the retried deliveries
+func NewEvent(eventType string, data interface{}) *Event {
+	id, err := gonanoid.New(30)
+	if err != nil {
+		// The ID is only used to deduplicate, the time is unique enough when random is not available
+		id = time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return &Event{
+		ID:   id,
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+}
+
+func NewFileEvent(eventType string, file *model.File) *Event {
+	return NewEvent(eventType, FileData{
+		ID:        file.ID,
+		Token:     file.Token,
+		Filename:  file.Filename,
+		FileSize:  file.FileSize,
+		FileType:  file.FileType,
+		BundleID:  file.BundleID,
+		IsPaste:   file.IsPaste,
+		ExpiredAt: file.ExpiredAt,
+	})
+}
+
+func NewImageEvent(eventType string, image *model.Image) *Event {
+	return NewEvent(eventType, ImageData{
+		ID:               image.ID,
+		OriginalFilename: image.OriginalFilename,
+		FileSize:         image.FileSize,
+		FilePath:         image.FilePath,
+	})
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/fetch"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPDispatcher posts the signed events to the webhooks.
+// The webhook URL is given by the user, so it is only allowed to connect to the public addresses.
+type HTTPDispatcher struct {
+	log         *zap.SugaredLogger
+	store       data.WebhookDataStore
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	wg          sync.WaitGroup
+	// ctx is cancelled when Wait gives up, so the pending deliveries stop
+	ctx    context.Context
+	cancel context.CancelFunc
+	// allowPrivate disables the SSRF protection, only used in tests
+	allowPrivate bool
+}
+
+// NewHTTPDispatcher creates the dispatcher that tries to deliver the event at most maxAttempts times.
+// The wait before the next attempt starts from backoff and doubles after every attempt.
+func NewHTTPDispatcher(l *zap.SugaredLogger, store data.WebhookDataStore, maxAttempts int, backoff time.Duration, timeout time.Duration) *HTTPDispatcher {
+	d := &HTTPDispatcher{
+		log:         l.Named("webhook"),
+		store:       store,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           fetch.NewDialer(d.checkAddress).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		// The redirect is not followed, the webhook must respond with the success status itself
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return d
+}
+
+func (d *HTTPDispatcher) Dispatch(userID uint, event *Event) {
+	// Anonymous user cannot have webhook
+	if userID == 0 {
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		// The request context can be done before the event is delivered
+		ctx := d.ctx
+		webhooks, err := d.store.FindByUserIDAndEvent(ctx, userID, event.Type)
+		if err != nil {
+			d.log.Errorw("unable to find webhooks", "error", err, "userID", userID, "event", event.Type)
+			return
+		}
+		for i := range webhooks {
+			d.wg.Add(1)
+			go func(webhook *model.Webhook) {
+				defer d.wg.Done()
+				d.deliverWithRetry(ctx, webhook, event)
+			}(&webhooks[i])
+		}
+	}()
+}
+
+func (d *HTTPDispatcher) Deliver(ctx context.Context, webhook *model.Webhook, event *Event) *model.WebhookDelivery {
+	delivery, _ := d.deliver(ctx, webhook, event, 1)
+	return delivery
+}
+
+// Wait waits for all dispatched events to be delivered or run out of attempts until the context is done.
+// The deliveries still pending then are abandoned.
+func (d *HTTPDispatcher) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+	}
+}
+
+func (d *HTTPDispatcher) deliverWithRetry(ctx context.Context, webhook *model.Webhook, event *Event) {
+	for attempt := 1; ; attempt++ {
+		_, err := d.deliver(ctx, webhook, event, attempt)
+		if err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			d.log.Warnw("abandon webhook delivery", "webhookID", webhook.ID, "eventID", event.ID, "attempts", attempt)
+			return
+		}
+		// The forbidden address does not change on retry
+		if attempt >= d.maxAttempts || errors.Is(err, fetch.ErrForbiddenAddress) {
+			d.log.Infow("unable to deliver webhook event", "webhookID", webhook.ID, "eventID", event.ID, "attempts", attempt, "error", err)
+			return
+		}
+		timer := time.NewTimer(d.backoff << (attempt - 1))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			d.log.Warnw("abandon webhook delivery", "webhookID", webhook.ID, "eventID", event.ID, "attempts", attempt)
+			return
+		}
+	}
+}
+
+// deliver sends the event and records the result of the attempt
+func (d *HTTPDispatcher) deliver(ctx context.Context, webhook *model.Webhook, event *Event, attempt int) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{
+		CreatedAt: time.Now().UTC(),
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		Event:     event.Type,
+		Attempt:   attempt,
+	}
+	start := time.Now()
+	statusCode, err := d.send(ctx, webhook, event)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	delivery.StatusCode = statusCode
+	delivery.Success = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	// The attempt interrupted by Wait is not the response of the webhook
+	if ctx.Err() != nil {
+		return delivery, err
+	}
+	if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+		d.log.Errorw("unable to save webhook delivery", "error", err, "webhookID", webhook.ID, "eventID", event.ID)
+	}
+	return delivery, err
+}
+
+func (d *HTTPDispatcher) send(ctx context.Context, webhook *model.Webhook, event *Event) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cscms-storage")
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, time.Now(), payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if errors.Is(err, fetch.ErrForbiddenAddress) {
+			return 0, fetch.ErrForbiddenAddress
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Read the small response to reuse the connection
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *HTTPDispatcher) checkAddress(address string) error {
+	if d.allowPrivate {
+		return nil
+	}
+	return fetch.CheckPublicAddress(address)
+}
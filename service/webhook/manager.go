@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"time"
+)
+
+const (
+	EventFileCreated    = "file.created"
+	EventFileDownloaded = "file.downloaded"
+	EventFileExpired    = "file.expired"
+	EventFileDeleted    = "file.deleted"
+	EventImageCreated   = "image.created"
+	// EventTest is only sent by the test-fire endpoint, it cannot be subscribed
+	EventTest = "webhook.test"
+)
+
+// EventTypes are the event types that can be subscribed
+var EventTypes = []string{EventFileCreated, EventFileDownloaded, EventFileExpired, EventFileDeleted, EventImageCreated}
+
+// Event is the payload sent to the webhook
+type Event struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// FileData is the data of the file events
+type FileData struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"`
+	Filename  string    `json:"filename"`
+	FileSize  uint64    `json:"file_size"`
+	FileType  string    `json:"file_type"`
+	BundleID  *string   `json:"bundle_id,omitempty"`
+	IsPaste   bool      `json:"is_paste"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// ImageData is the data of the image events
+type ImageData struct {
+	ID               uint   `json:"id"`
+	OriginalFilename string `json:"original_filename"`
+	FileSize         uint64 `json:"file_size"`
+	FilePath         string `json:"file_path"`
+}
+
+type Dispatcher interface {
+	// Dispatch delivers the event to the webhooks of the user subscribed to its type in the background.
+	// Failed deliveries are retried with backoff.
+	Dispatch(userID uint, event *Event)
+	// Deliver sends the event to the webhook once and records the delivery
+	Deliver(ctx context.Context, webhook *model.Webhook, event *Event) *model.WebhookDelivery
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"github.com/thetkpark/cscms-temp-storage/data"
+	"github.com/thetkpark/cscms-temp-storage/data/model"
+	"github.com/thetkpark/cscms-temp-storage/service/fetch"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestDispatcher(t *testing.T) (*HTTPDispatcher, *data.GormWebhookDataStore) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	store, err := data.NewGormWebhookDataStore(db)
+	require.NoError(t, err)
+	dispatcher := NewHTTPDispatcher(zap.NewNop().Sugar(), store, 3, time.Millisecond, time.Second)
+	dispatcher.allowPrivate = true
+	return dispatcher, store
+}
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"id":"1"}`)
+	signature := Sign("secret", time.Now(), payload)
+	require.NoError(t, Verify("secret", signature, payload, time.Minute))
+	require.ErrorIs(t, Verify("other", signature, payload, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", signature, []byte(`{"id":"2"}`), time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", Sign("secret", time.Now().Add(-time.Hour), payload), payload, time.Minute), ErrInvalidSignature)
+	require.ErrorIs(t, Verify("secret", "v1=abc", payload, time.Minute), ErrInvalidSignature)
+}
+
+func TestDispatch(t *testing.T) {
+	var requests int32
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails and is retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		payload, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, Verify("secret", r.Header.Get(HeaderSignature), payload, time.Minute))
+		require.Equal(t, EventFileCreated, r.Header.Get(HeaderEventType))
+		var event Event
+		require.NoError(t, json.Unmarshal(payload, &event))
+		received <- event
+	}))
+	defer server.Close()
+
+	dispatcher, store := newTestDispatcher(t)
+	ctx := context.Background()
+	webhook := &model.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: EventFileCreated}
+	require.NoError(t, store.Create(ctx, webhook))
+	// The event is not sent to the webhook that is not subscribed
+	require.NoError(t, store.Create(ctx, &model.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: EventFileDeleted}))
+
+	event := NewFileEvent(EventFileCreated, &model.File{ID: "file", Filename: "a.txt"})
+	dispatcher.Dispatch(1, event)
+	dispatcher.Dispatch(0, event)
+	dispatcher.Wait(context.Background())
+
+	receivedEvent := <-received
+	require.Equal(t, event.ID, receivedEvent.ID)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	deliveries, err := store.FindDeliveries(ctx, webhook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.True(t, deliveries[0].Success)
+	require.Equal(t, 2, deliveries[0].Attempt)
+	require.False(t, deliveries[1].Success)
+	require.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+}
+
+func TestDispatchGiveUp(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dispatcher, store := newTestDispatcher(t)
+	webhook := &model.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: EventFileDeleted}
+	require.NoError(t, store.Create(context.Background(), webhook))
+
+	dispatcher.Dispatch(1, NewEvent(EventFileDeleted, nil))
+	dispatcher.Wait(context.Background())
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestWaitAbandon(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dispatcher, store := newTestDispatcher(t)
+	dispatcher.backoff = time.Hour
+	webhook := &model.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: EventFileDeleted}
+	require.NoError(t, store.Create(context.Background(), webhook))
+
+	// The delivery waiting for the next attempt is abandoned when the wait times out
+	dispatcher.Dispatch(1, NewEvent(EventFileDeleted, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	dispatcher.Wait(ctx)
+	require.Less(t, time.Since(start), time.Minute)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDeliverPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dispatcher, store := newTestDispatcher(t)
+	dispatcher.allowPrivate = false
+	webhook := &model.Webhook{UserID: 1, URL: server.URL, Secret: "secret", Events: EventFileDeleted}
+	require.NoError(t, store.Create(context.Background(), webhook))
+
+	delivery := dispatcher.Deliver(context.Background(), webhook, NewEvent(EventTest, nil))
+	require.False(t, delivery.Success)
+	require.Equal(t, fetch.ErrForbiddenAddress.Error(), delivery.Error)
+}